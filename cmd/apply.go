@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bladeacer/mmsync/config"
+	"github.com/bladeacer/mmsync/health"
+	"github.com/bladeacer/mmsync/syncer"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan-file>",
+	Short: "Carries out a sync plan written by mmsync sync --plan",
+	Long: `Carries out exactly the changes of a plan written by mmsync sync --plan, one commit per
+alias, the same way mmsync sync would.
+
+Before anything is copied every alias of the plan is checked. The plan is refused when a
+file in a tracked directory changed, a repository has new commits or different uncommitted
+changes in the alias folder, or an entry was removed, re-pointed or moved to another
+repository since the plan was made. Make a new plan with mmsync sync --plan then.
+
+Examples:
+
+mmsync sync tag:private --plan plan.json
+mmsync apply plan.json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+		if err := health.RequireBinaries("git"); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		plan, err := syncer.LoadPlan(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if profile := config.ActiveProfile(); plan.Profile != profile {
+			fmt.Printf("Error: the plan was made for profile '%s', not '%s'. Run it with --profile %s.\n", plan.Profile, profile, plan.Profile)
+			os.Exit(1)
+		}
+
+		ctx, stop := syncContext()
+		defer stop()
+
+		jobs, problems := planJobs(ctx, plan)
+		if len(problems) > 0 {
+			fmt.Println("Error: the plan is out of date:")
+			for _, problem := range problems {
+				fmt.Printf("  %s\n", problem)
+			}
+			fmt.Println("Make a new one with mmsync sync --plan.")
+			os.Exit(1)
+		}
+
+		printPlan(plan)
+		if len(jobs) == 0 {
+			return
+		}
+		fmt.Println()
+
+		display := newSyncDisplay(jobs)
+		opts := syncer.Options{StateDir: syncer.StateDir(appConf), Progress: display.progress}
+		results := syncer.ExecuteAll(ctx, jobs, plan.Aliases, opts, syncWorkers(), display.done)
+		display.finish()
+		finishSync(jobs, results, opts.StateDir)
+	},
+}
+
+// Matches every alias of the plan to its tracked entry and checks that
+// nothing changed since the plan was made. Returns the jobs in plan order, or
+// what changed.
+func planJobs(ctx context.Context, plan *syncer.Plan) ([]syncer.Job, []string) {
+	var jobs []syncer.Job
+	var problems []string
+	for _, a := range plan.Aliases {
+		name := fmt.Sprintf("'%s' (ID: %s)", a.Alias, a.ID)
+		entry, ok := dataStore.TrackedDirs[a.ID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is no longer tracked", name))
+			continue
+		}
+		repo, err := appConf.RepositoryFor(entry)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+
+		job := syncer.Job{ID: a.ID, Entry: entry, Repo: repo}
+		if err := a.Verify(ctx, job, syncer.Options{Rehash: plan.Rehash, Xattrs: plan.Xattrs}); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, problems
+}
+
+// Works out what syncing jobs would do, prints it and saves it to path.
+// Nothing is saved when any directory cannot be planned.
+func writeSyncPlan(ctx context.Context, jobs []syncer.Job, opts syncer.Options, path string) {
+	aliases, errs := syncer.PlanAll(ctx, jobs, opts, syncWorkers())
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			fmt.Printf("%s (ID: %s): Error: %v\n", jobs[i].Entry.Alias, jobs[i].ID, err)
+			failed++
+		}
+	}
+	if ctx.Err() != nil {
+		fmt.Println("\nInterrupted, no plan was written.")
+		os.Exit(130)
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d directories could not be planned, no plan was written.\n", failed, len(jobs))
+		os.Exit(1)
+	}
+
+	plan := &syncer.Plan{
+		PlanVersion: syncer.PlanVersion,
+		CreatedAt:   time.Now().UTC(),
+		Profile:     config.ActiveProfile(),
+		Rehash:      opts.Rehash,
+		Xattrs:      opts.Xattrs,
+		Aliases:     aliases,
+	}
+	printPlan(plan)
+	if err := plan.Save(path); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nPlan written to %s. Carry it out with mmsync apply %s\n", path, path)
+}
+
+// Prints every change of the plan, alias by alias, and the totals.
+func printPlan(plan *syncer.Plan) {
+	var total syncer.Changes
+	for i, a := range plan.Aliases {
+		if i > 0 {
+			fmt.Println()
+		}
+		c := a.Changes
+		fmt.Printf("%s (ID: %s) -> %s:%s\n", a.Alias, a.ID, a.Repo, a.Alias)
+		if a.RepoStatus != "" {
+			fmt.Printf("  ! uncommitted changes in the repository copy will be discarded\n")
+		}
+		for _, path := range c.Added {
+			fmt.Printf("  + %s\n", path)
+		}
+		for _, path := range c.Modified {
+			fmt.Printf("  ~ %s\n", path)
+		}
+		for _, path := range c.Deleted {
+			fmt.Printf("  - %s\n", path)
+		}
+		for _, r := range c.Renamed {
+			fmt.Printf("  > %s -> %s\n", r.From, r.To)
+		}
+		for _, path := range c.Metadata {
+			fmt.Printf("  * %s (metadata)\n", path)
+		}
+		if c.Empty() {
+			fmt.Println("  up to date")
+		} else {
+			fmt.Printf("  %s\n", c.Summary())
+		}
+
+		total.Added = append(total.Added, c.Added...)
+		total.Modified = append(total.Modified, c.Modified...)
+		total.Deleted = append(total.Deleted, c.Deleted...)
+		total.Renamed = append(total.Renamed, c.Renamed...)
+		total.Metadata = append(total.Metadata, c.Metadata...)
+	}
+	fmt.Printf("\nPlan for %d directories, made %s: %s\n", len(plan.Aliases), plan.CreatedAt.Local().Format("2006-01-02 15:04:05"), total.Summary())
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().IntVarP(&syncJobsFlag, "jobs", "j", 0, "Directories synced at the same time (default sync.jobs).")
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bladeacer/mmsync/config"
+	"github.com/bladeacer/mmsync/health"
+	"github.com/spf13/cobra"
+)
+
+var bootstrapDestFlag string
+var bootstrapRestoreFlag bool
+
+// Matches scp-like git remotes such as git@example.com:backups.git
+var scpRemotePattern = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
+var bootstrapCmd = &cobra.Command{
+	Use:   "bootstrap <repo-path-or-url>",
+	Short: "Rebuilds tracking from the manifest committed to a target repository",
+	Long: `Reads the manifest mmsync keeps in a target repository and tracks its entries again.
+Use it on a new machine to recover where every alias came from.
+
+A URL is cloned first, into --dest or a directory named after the repository.
+An uninitialized profile is initialized with the repository as repo_path. Otherwise the
+repository must already be configured, as repo_path or with mmsync repo add.
+
+Entries whose alias or path is already tracked are reported and skipped.
+
+Examples:
+
+mmsync bootstrap ~/backups
+mmsync bootstrap git@example.com:me/backups.git --dest ~/backups --restore`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, err := bootstrapRepoPath(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		manifest, err := config.ReadManifest(repoPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		repo, err := bootstrapRepository(repoPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		added, skipped := mergeManifest(manifest, repo)
+		if err := saveDataStore(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nTracked %d entries from %s, skipped %d.\n", len(added), config.ManifestPath(repoPath), skipped)
+
+		if bootstrapRestoreFlag {
+			restoreEntries(added, true, false)
+		}
+	},
+}
+
+// Resolves a local repository path, cloning it first when given a URL.
+func bootstrapRepoPath(source string) (string, error) {
+	if _, err := os.Stat(source); err != nil && (strings.Contains(source, "://") || scpRemotePattern.MatchString(source)) {
+		dest := bootstrapDestFlag
+		if dest == "" {
+			dest = strings.TrimSuffix(filepath.Base(strings.TrimRight(source, "/")), ".git")
+		}
+		if err := health.RequireBinaries("git"); err != nil {
+			return "", fmt.Errorf("cannot clone %s: %w", source, err)
+		}
+
+		fmt.Printf("Cloning %s into %s\n", source, dest)
+		clone := exec.Command("git", "clone", source, dest)
+		clone.Stdout = os.Stdout
+		clone.Stderr = os.Stderr
+		if err := clone.Run(); err != nil {
+			return "", fmt.Errorf("git clone failed: %w", err)
+		}
+		source = dest
+	}
+
+	repoPath, err := processRepoPath(source)
+	if err != nil {
+		return "", err
+	}
+	if exists, _ := config.GitDirExists(repoPath); !exists {
+		return "", fmt.Errorf("directory '%s' does not exist", filepath.Join(repoPath, ".git"))
+	}
+	return repoPath, nil
+}
+
+// Finds the configured repository at repoPath, initializing the active profile
+// with it when the profile is not initialized yet.
+func bootstrapRepository(repoPath string) (config.Repository, error) {
+	if !appConf.ConfigSchema.IsInit {
+		appConf.ConfigSchema.IsInit = true
+		appConf.ConfigSchema.RepoPath = repoPath
+		if err := appConf.SaveConfig(config.ResolveConfigPath()); err != nil {
+			return config.Repository{}, fmt.Errorf("failed to initialize configuration: %w", err)
+		}
+		fmt.Printf("Initialized configuration at %s with repo_path %s\n", config.ResolveConfigPath(), repoPath)
+		return appConf.DefaultRepository(), nil
+	}
+
+	for _, repo := range appConf.Repositories() {
+		if repo.Path == repoPath {
+			return repo, nil
+		}
+	}
+	return config.Repository{}, fmt.Errorf("repository '%s' is not configured for profile '%s'. Add it with mmsync repo add <name> %s", repoPath, config.ActiveProfile(), repoPath)
+}
+
+// Adds the manifest entries to the database, routed to repo. IDs are kept when
+// they are free. Returns the IDs of the added entries and the number skipped.
+func mergeManifest(manifest *config.Manifest, repo config.Repository) ([]string, int) {
+	var added []string
+	skipped := 0
+
+	for _, id := range manifest.IDs() {
+		entry := manifest.TrackedDirs[id]
+		path, err := entry.Path()
+		if err == nil {
+			err = checkEntryConflict(path, entry.Alias)
+		}
+		if err != nil {
+			fmt.Printf("Skipped '%s': %v\n", entry.Alias, err)
+			skipped++
+			continue
+		}
+
+		// Manifests written before paths were stored portably hold absolute paths
+		if filepath.IsAbs(entry.TargetPath) {
+			entry.TargetPath = config.PortablePath(entry.TargetPath)
+		}
+		entry.Repo = ""
+		if repo.Name != config.DefaultRepoName {
+			entry.Repo = repo.Name
+		}
+
+		newID := insertEntry(id, entry)
+		fmt.Printf("Tracking '%s' at %s (ID: %s)\n", entry.Alias, path, newID)
+		added = append(added, newID)
+	}
+
+	return added, skipped
+}
+
+func init() {
+	rootCmd.AddCommand(bootstrapCmd)
+
+	bootstrapCmd.Flags().StringVarP(&bootstrapDestFlag, "dest", "d", "", "Directory to clone a repository URL into.")
+	bootstrapCmd.Flags().BoolVar(&bootstrapRestoreFlag, "restore", false, "Copy each entry's folder from the repository back to its path, as mmsync restore does.")
+}
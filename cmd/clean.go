@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bladeacer/mmsync/config"
+	"github.com/bladeacer/mmsync/health"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cleanRepoFlag   string
+	cleanRemoveFlag bool
+	cleanYesFlag    bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Lists and removes repository entries that mirror no tracked directory",
+	Long: `Lists the top-level files and folders of the repositories that are not the copy of a
+tracked directory, such as folders left behind by removed or renamed aliases. The .git and
+.mmsync directories and names matching clean.keep are never listed. By default clean.keep
+holds dotfiles such as .github and .gitignore, README*, LICENSE*, COPYING*, CHANGELOG* and
+Makefile.
+
+With --remove the orphans are deleted after confirmation, with git rm when git tracks them so
+the removal is staged for the next commit. --yes skips the confirmation.
+
+Examples:
+
+mmsync clean
+mmsync clean --repo team --remove
+mmsync config set clean.keep README*,docs`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+		if err := health.RequireBinaries("git"); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		repos := appConf.Repositories()
+		if cleanRepoFlag != "" {
+			repo, err := appConf.Repository(cleanRepoFlag)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			repos = []config.Repository{repo}
+		}
+
+		var orphans []config.Orphan
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tNAME\tTYPE\tGIT\tPATH")
+		for _, repo := range repos {
+			found, err := appConf.Orphans(repo, dataStore)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			for _, orphan := range found {
+				kind, git := "file", "untracked"
+				if orphan.IsDir {
+					kind = "folder"
+				}
+				if orphan.InGit() {
+					git = "tracked"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", repo.Name, orphan.Name, kind, git, orphan.Path)
+			}
+			orphans = append(orphans, found...)
+		}
+
+		if len(orphans) == 0 {
+			fmt.Println("No orphaned entries.")
+			return
+		}
+		w.Flush()
+
+		if !cleanRemoveFlag {
+			fmt.Printf("\n%d orphaned entries. Run mmsync clean --remove to delete them.\n", len(orphans))
+			return
+		}
+		if !cleanYesFlag && !confirm(fmt.Sprintf("\nRemove %d orphaned entries?", len(orphans))) {
+			fmt.Println("Aborted.")
+			return
+		}
+
+		failed := 0
+		for _, orphan := range orphans {
+			if err := orphan.Remove(); err != nil {
+				failed++
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			fmt.Printf("Removed %s\n", orphan.Path)
+		}
+		fmt.Println("Commit the removals in each repository to record them.")
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+
+	cleanCmd.Flags().StringVarP(&cleanRepoFlag, "repo", "r", "", "Only clean the named repository.")
+	cleanCmd.Flags().BoolVar(&cleanRemoveFlag, "remove", false, "Remove the orphaned entries after confirmation.")
+	cleanCmd.Flags().BoolVarP(&cleanYesFlag, "yes", "y", false, "Remove without asking for confirmation.")
+}
@@ -1,38 +1,49 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"github.com/bladeacer/mmsync/config"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 )
 
 // TODO: This command helps add directory paths to be staged before performing backup. Have CRUD in this.
 // Somehow rsync directories to the target directory and then tar archive all of them when push is called
 
 var aliases []string
+var addRepoFlag string
+var addContinueFlag bool
+var addTagsFlag []string
 var addCmd = &cobra.Command{
 	Use:   "add [path_1] [path_2]...",
 	Short: "Add one or more target paths to be tracked for backup",
 	Long: `Add one or more target paths to be tracked for backup.
 If provided, the number of aliases must match the number of paths.
+Nothing is added when any path is rejected, unless --continue-on-error is given.
 
 Examples:
 
 mmsync add ./
 mmsync add ./ --alias="test"
 mmsync add ./ ~/test_dir --alias="test","test_dir_w_alias"
+mmsync add ~/shared-notes --repo team
+mmsync add ~/.config/nvim ~/.config/git --tag dotfiles
+find ~/projects -maxdepth 1 -name '*-notes' | mmsync add -
 
-Adds the current directory recursively to be staged.`,
+Adds the current directory recursively to be staged.
+With - as the only path, newline-separated paths are read from stdin.`,
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		configPath := config.ResolveConfigPath()
 		isInit := appConf.ConfigSchema.IsInit
 
 		if !isInit {
-			fmt.Printf("\nConfiguration file not found at expected path\n%s\nRun mmsync init to start.\n", configPath)
+			fmt.Printf("\nConfiguration file not found at expected path\n%s\nRun %s to start.\n", configPath, initCommand())
 		} else {
 			addWrapper(args)
 			fmt.Println("\nFinished adding entries.")
@@ -42,16 +53,44 @@ Adds the current directory recursively to be staged.`,
 }
 
 func addWrapper(args []string) {
+	if len(args) == 1 && args[0] == "-" {
+		var err error
+		if args, err = readPathsFromStdin(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	if len(aliases) > 0 && len(aliases) != len(args) {
 		fmt.Fprintf(os.Stderr, "Error: Number of paths (%d) must match number of aliases (%d).\n", len(args), len(aliases))
 		os.Exit(1)
 	}
 
+	for _, tag := range addTagsFlag {
+		if err := config.ValidateTag(tag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	repo := appConf.DefaultRepository()
+	if addRepoFlag != "" {
+		var err error
+		if repo, err = appConf.Repository(addRepoFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Every path is validated and added in memory first, so a rejected path
+	// leaves the database untouched unless --continue-on-error is given
+	var added []string
+	var rejected []addRejection
 	for i, argPath := range args {
 		resolvedPath, err := resolveAndValidatePath(argPath)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing path '%s': %v\n", argPath, err)
-			os.Exit(1)
+			rejected = append(rejected, addRejection{path: argPath, reason: err})
+			continue
 		}
 
 		var alias string
@@ -60,12 +99,78 @@ func addWrapper(args []string) {
 		} else {
 			alias = filepath.Base(resolvedPath)
 		}
-		err = addDirectoryEntry(resolvedPath, alias)
+		newID, err := addDirectoryEntry(resolvedPath, alias, repo)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Fatal Error adding path '%s': %v\n", argPath, err)
+			rejected = append(rejected, addRejection{path: argPath, reason: err})
+			continue
+		}
+		added = append(added, newID)
+	}
+
+	if len(rejected) > 0 && !addContinueFlag {
+		printRejections(rejected)
+		fmt.Fprintln(os.Stderr, "\nNo entries were added. Fix the paths above or use --continue-on-error to add the valid ones.")
+		os.Exit(1)
+	}
+
+	if len(added) > 0 {
+		if err := saveDataStore(); err != nil {
+			fmt.Fprintf(os.Stderr, "Fatal Error saving added entries: %v\n", err)
 			os.Exit(1)
 		}
 	}
+
+	for _, id := range added {
+		entry := dataStore.TrackedDirs[id]
+		fmt.Printf("Successfully added directory:\n")
+		fmt.Printf("\tID: %s\n", id)
+		fmt.Printf("\tPath: %s\n", entry.TargetPath)
+		fmt.Printf("\tAlias: %s\n", entry.Alias)
+		fmt.Printf("\tRepository: %s\n", repo.Name)
+		if len(entry.Tags) > 0 {
+			fmt.Printf("\tTags: %s\n", strings.Join(entry.Tags, ", "))
+		}
+	}
+
+	if len(rejected) > 0 {
+		fmt.Println()
+		printRejections(rejected)
+		fmt.Fprintf(os.Stderr, "\nAdded %d of %d paths.\n", len(added), len(args))
+		os.Exit(1)
+	}
+}
+
+// A path refused by add, with the reason.
+type addRejection struct {
+	path   string
+	reason error
+}
+
+func printRejections(rejected []addRejection) {
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REJECTED PATH\tREASON")
+	for _, r := range rejected {
+		fmt.Fprintf(w, "%s\t%v\n", r.path, r.reason)
+	}
+	w.Flush()
+}
+
+// Reads newline-separated paths from stdin, skipping blank lines.
+func readPathsFromStdin() ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading paths from stdin: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths given on stdin")
+	}
+	return paths, nil
 }
 
 func resolveAndValidatePath(path string) (string, error) {
@@ -93,50 +198,78 @@ func resolveAndValidatePath(path string) (string, error) {
 	if !info.IsDir() {
 		return "", fmt.Errorf("path '%s' is a file, only directories can be added", targetPath)
 	}
-	if targetPath == appConf.ConfigSchema.RepoPath {
-		return "", fmt.Errorf("Cannot circular reference repo path: '%s'", targetPath)
-	}
-	if filepath.Dir(targetPath) == filepath.Dir(appConf.ConfigSchema.ConfigPath) {
-		return "", fmt.Errorf("Cannot circular reference config path: '%s'", targetPath)
-	}
-	if filepath.Base(targetPath) == "mnemosync" {
-		return "", fmt.Errorf("Do not the dev repo: '%s'", targetPath)
+	if err := appConf.CheckDenyRules(targetPath); err != nil {
+		return "", err
 	}
 
 	return targetPath, nil
 }
 
-func addDirectoryEntry(targetPath string, alias string) error {
-	for newID, entry := range dataStore.TrackedDirs {
-		if entry.TargetPath == targetPath {
+// Reports an existing entry that already tracks the absolute targetPath or uses alias, or an
+// alias that cannot name a folder in the repository.
+func checkEntryConflict(targetPath string, alias string) error {
+	if alias == config.MetadataDir || alias == ".git" || alias == "." || alias == ".." || strings.ContainsRune(alias, filepath.Separator) {
+		return fmt.Errorf("alias '%s' is reserved or not a valid folder name", alias)
+	}
+
+	for id, entry := range dataStore.TrackedDirs {
+		if path, err := entry.Path(); err == nil && path == targetPath {
 			return fmt.Errorf("path '%s' is already being tracked (ID: %s, Alias: %s)",
-				targetPath, newID, entry.Alias)
+				targetPath, id, entry.Alias)
 		}
 
 		if entry.Alias == alias {
 			return fmt.Errorf("alias '%s' is already in use by path '%s' (ID: %s)",
-				alias, entry.TargetPath, newID)
+				alias, entry.TargetPath, id)
 		}
 	}
+	return nil
+}
 
-	dbPath := config.ResolveDbPath()
+// Adds an entry to the database in memory and returns its ID. The caller saves.
+func addDirectoryEntry(targetPath string, alias string, repo config.Repository) (string, error) {
+	if err := checkEntryConflict(targetPath, alias); err != nil {
+		return "", err
+	}
 
 	newEntry := config.DirData{
-		TargetPath: targetPath,
+		TargetPath: config.PortablePath(targetPath),
 		Alias:      alias,
 	}
+	if repo.Name != config.DefaultRepoName {
+		newEntry.Repo = repo.Name
+	}
+	for _, tag := range addTagsFlag {
+		newEntry.AddTag(tag)
+	}
 
-	newID := dataStore.AddDir(newEntry)
+	return dataStore.AddDir(newEntry), nil
+}
 
-	if err := dataStore.SaveData(dbPath); err != nil {
-		return fmt.Errorf("failed to save data store after adding entry: %w", err)
+// Adds an entry under id when that ID is free and numeric, and under a new ID
+// otherwise. Returns the ID used.
+func insertEntry(id string, entry config.DirData) string {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if _, taken := dataStore.TrackedDirs[id]; taken || err != nil || n <= 0 || strconv.FormatInt(n, 10) != id {
+		return dataStore.AddDir(entry)
 	}
 
-	fmt.Printf("Successfully added directory:\n")
-	fmt.Printf("\tID: %s\n", newID)
-	fmt.Printf("\tPath: %s\n", targetPath)
-	fmt.Printf("\tAlias: %s\n", alias)
+	dataStore.TrackedDirs[id] = entry
+	if n > dataStore.CurrentId {
+		dataStore.CurrentId = n
+	}
+	return id
+}
 
+// Saves the database of the active profile and refreshes the manifest
+// committed to each target repository.
+func saveDataStore() error {
+	if err := dataStore.SaveData(config.ResolveDbPath()); err != nil {
+		return err
+	}
+	if err := appConf.WriteManifests(dataStore); err != nil {
+		return fmt.Errorf("database saved, but the repository manifest was not updated: %w", err)
+	}
 	return nil
 }
 
@@ -144,4 +277,7 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 
 	addCmd.Flags().StringSliceVarP(&aliases, "alias", "a", []string{}, "Comma-separated list of aliases for the corresponding paths.")
+	addCmd.Flags().BoolVar(&addContinueFlag, "continue-on-error", false, "Add the valid paths even when others are rejected.")
+	addCmd.Flags().StringSliceVarP(&addTagsFlag, "tag", "t", []string{}, "Comma-separated list of tags for every added path.")
+	addCmd.Flags().StringVarP(&addRepoFlag, "repo", "r", "", "Name of the target repository (default is default_repo).")
 }
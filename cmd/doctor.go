@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bladeacer/mmsync/health"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorFixFlag    bool
+	doctorYesFlag    bool
+	doctorDryRunFlag bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Runs the health checks and repairs what it can",
+	Long: `Runs the health checks and lists the fixes available for the problems found, such as
+creating a missing database, setting the git identity of a repository, re-pointing a tracked
+directory that has moved, removing orphaned folders from a repository, with git rm when git
+tracks them, and removing write access for other users from the configuration file.
+
+With --fix, each fix is applied after a prompt. --yes applies every fix without asking and
+--dry-run only shows what --fix would do. Fixes that run git fail when git is missing or too
+old; the others are still applied.
+
+Exit status is that of mmsync health once the fixes have been applied.
+
+Examples:
+
+mmsync doctor
+mmsync doctor --fix
+mmsync doctor --fix --yes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		report := RunHealthCheck()
+		printHealthReport(report)
+
+		fixes := report.Fixes()
+		if len(fixes) == 0 {
+			fmt.Println("\nNothing to fix.")
+			os.Exit(report.Status.ExitCode())
+		}
+
+		if doctorDryRunFlag || !(doctorFixFlag || doctorYesFlag) {
+			fmt.Printf("\n%d fixes available:\n", len(fixes))
+			for _, fix := range fixes {
+				fmt.Printf("  %s\n", fix.Description)
+			}
+			if !doctorDryRunFlag {
+				fmt.Println("\nRun mmsync doctor --fix to apply them.")
+			}
+			os.Exit(report.Status.ExitCode())
+		}
+
+		fmt.Println()
+		applied, failed := applyFixes(fixes)
+		if applied == 0 && failed == 0 {
+			fmt.Println("No fixes applied.")
+			os.Exit(report.Status.ExitCode())
+		}
+
+		report = RunHealthCheck()
+		counts := report.Counts()
+		fmt.Printf("\nApplied %d fixes, %d failed. Health is now: %d passed, %d warnings, %d failed, %d skipped\n",
+			applied, failed, counts[health.StatusPass], counts[health.StatusWarn], counts[health.StatusFail], counts[health.StatusSkip])
+		os.Exit(report.Status.ExitCode())
+	},
+}
+
+// Applies each fix, asking first unless --yes was given. A failing fix does
+// not stop the ones after it.
+func applyFixes(fixes []health.Fix) (int, int) {
+	applied, failed := 0, 0
+	for _, fix := range fixes {
+		if !doctorYesFlag && !confirm(fix.Description+"?") {
+			continue
+		}
+		if err := fix.Apply(); err != nil {
+			failed++
+			fmt.Printf("Error: %s: %v\n", fix.Description, err)
+			continue
+		}
+		applied++
+		fmt.Printf("Done: %s\n", fix.Description)
+	}
+	return applied, failed
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorFixFlag, "fix", false, "Apply the available fixes, asking before each one.")
+	doctorCmd.Flags().BoolVarP(&doctorYesFlag, "yes", "y", false, "Apply every fix without asking. Implies --fix.")
+	doctorCmd.Flags().BoolVar(&doctorDryRunFlag, "dry-run", false, "Only show the fixes --fix would apply.")
+}
@@ -1,144 +1,95 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/bladeacer/mmsync/config"
+	"github.com/bladeacer/mmsync/health"
 	"github.com/spf13/cobra"
 	"os"
-	"os/exec"
 	"strings"
 )
 
+var healthOutputFlag string
+
 // healthCmd represents the health command
 var healthCmd = &cobra.Command{
-	Use:   "health",
-	Short: "Checks the health of mnemosync",
+	Use:         "health",
+	Short:       "Checks the health of mnemosync",
+	Annotations: readOnlyCommand,
 	Long: `Checks the health of mnemosync
 Checks if the required system binaries are installed
 
-Also checks if the mnemosync configuration files have been created.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		RunHealthCheck(true)
-	},
-}
-
-func RunHealthCheck(shouldPrintOutput bool) string {
-	var errStrBuilder strings.Builder
-	separator := "_"
-	repeatedSeparator := strings.Repeat(separator, 72)
-
-	configPath := appConf.ConfigSchema.ConfigPath
-	repoPath := appConf.ConfigSchema.RepoPath
-	dbPath := appConf.ConfigSchema.DbPath
+Also checks if the mnemosync configuration files have been created.
 
-	fmt.Println("\n\tRunning Health Check")
-
-	if err := checkBinWrapper("git", false); err != "" {
-		errStrBuilder.WriteString(err)
-	}
-	if err := checkBinWrapper("rsync", false); err != "" {
-		errStrBuilder.WriteString(err)
-	}
-	if err := checkBinWrapper("tar", false); err != "" {
-		errStrBuilder.WriteString(err)
-	}
-	if err := checkBinWrapper("zip", true); err != "" {
-		errStrBuilder.WriteString(err)
-	}
-
-	fmt.Printf("\t%s\n\n", repeatedSeparator)
-
-	fmt.Println("\tConfiguration File:")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		msg := fmt.Sprintf("\t\t[NOT FOUND] Configuration file not found at:\n\t\t%s\n\t\tRun 'mmsync init' to start.\n", configPath)
-		errStrBuilder.WriteString(msg)
-		fmt.Print(msg)
-	} else {
-		fmt.Printf("\t\t[FOUND] at %s\n", configPath)
-	}
-	fmt.Printf("\t%s\n", repeatedSeparator)
-
-	fmt.Println("\tRepository Path:")
-	if repoPath == "" {
-		msg := "\t\t[NOT SET] Repository Path is not defined.\n\t\tRun 'mmsync init' to set.\n"
-		errStrBuilder.WriteString(msg)
-		fmt.Print(msg)
-	} else {
-		fmt.Printf("\t\t[SET] %s\n", repoPath)
-
-		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-			msg := fmt.Sprintf("\t\t[WARNING] Repository directory does not exist on disk: %s\n", repoPath)
-			errStrBuilder.WriteString(msg)
-			fmt.Print(msg)
+Exit status is 0 when every check passed, 1 when any check warned and 2 when any check failed.
+Use --output json for cron wrappers and monitoring.
+Problems listed as fixable can be repaired with mmsync doctor --fix.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if healthOutputFlag != "text" && healthOutputFlag != "json" {
+			fmt.Printf("Error: unknown output format '%s', expected text or json\n", healthOutputFlag)
+			os.Exit(1)
 		}
-	}
-	fmt.Printf("\t%s\n", repeatedSeparator)
 
-	fmt.Println("\tDatabase Path:")
-	if dbPath == "" {
-		msg := "\t\t[NOT SET] Database Path is not defined.\n\t\tRun 'mmsync init' to start.\n"
-		errStrBuilder.WriteString(msg)
-		fmt.Print(msg)
-	} else {
-		fmt.Printf("\t\t[SET] %s\n", dbPath)
-	}
+		report := RunHealthCheck()
 
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-		msg := fmt.Sprintf("\t\t[WARNING] Database file not found on disk: %s\n", dbPath)
-		errStrBuilder.WriteString(msg)
-		fmt.Print(msg)
-	}
+		if healthOutputFlag == "json" {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+		} else {
+			printHealthReport(report)
+		}
 
-	fmt.Printf("\t%s\n", repeatedSeparator)
-	fmt.Println("\n\tHealth Check Complete")
+		os.Exit(report.Status.ExitCode())
+	},
+}
 
-	return errStrBuilder.String()
+// RunHealthCheck runs every registered health check against the active profile.
+func RunHealthCheck() health.Report {
+	return health.Run(&health.Context{
+		Profile: config.ActiveProfile(),
+		Config:  appConf,
+		Data:    dataStore,
+	})
 }
 
-func checkBinWrapper(binaryName string, isOptional bool) string {
-	var msgBuilder strings.Builder
+func printHealthReport(report health.Report) {
+	repeatedSeparator := strings.Repeat("_", 72)
 
-	path, err := exec.LookPath(binaryName)
+	fmt.Println("\n\tRunning Health Check")
+	fmt.Printf("\tProfile: %s\n", report.Profile)
 
-	if err != nil {
-		if !isOptional {
-			return fmt.Sprintf("[FAIL] Required Binary '%s' not found in PATH.", binaryName)
+	group := ""
+	for _, c := range report.Checks {
+		if g, _, _ := strings.Cut(c.Name, "/"); g != group {
+			group = g
+			fmt.Printf("\t%s\n", repeatedSeparator)
 		}
-		return fmt.Sprintf("[WARNING] Optional Binary '%s' not found in PATH.", binaryName)
-	}
-
-	msgBuilder.WriteString(fmt.Sprintf("[PASS] Binary '%s' found at: %s\n", binaryName, path))
-
-	cmd := exec.Command(binaryName, "--version")
-	output, versionErr := cmd.CombinedOutput()
-
-	if versionErr != nil {
-		msgBuilder.WriteString(fmt.Sprintf("\t[WARNING] Version check failed for '%s'. ", binaryName))
 
-		if exitError, ok := versionErr.(*exec.ExitError); ok {
-			msgBuilder.WriteString(fmt.Sprintf("Exit Code %d. Output:\n\t\t%s\n", exitError.ExitCode(), strings.TrimSpace(string(output))))
-		} else {
-			msgBuilder.WriteString(fmt.Sprintf("Failed to execute: %v\n", versionErr))
+		fmt.Printf("\t\t[%s] %s: %s\n", strings.ToUpper(c.Status.String()), c.Name, c.Message)
+		for _, detail := range c.Details {
+			fmt.Printf("\t\t\t%s\n", detail)
+		}
+		if c.Hint != "" && c.Status > health.StatusPass {
+			fmt.Printf("\t\t\tHint: %s\n", c.Hint)
+		}
+		if len(c.Fixes) > 0 && c.Status > health.StatusPass {
+			fmt.Printf("\t\t\tFixable with mmsync doctor --fix (%d fixes)\n", len(c.Fixes))
 		}
-
-		return msgBuilder.String()
 	}
+	fmt.Printf("\t%s\n", repeatedSeparator)
 
-	versionLine := strings.SplitN(string(output), "\n", 2)[0]
-	msgBuilder.WriteString(fmt.Sprintf("\tVersion: %s\n", strings.TrimSpace(versionLine)))
-
-	return msgBuilder.String()
+	counts := report.Counts()
+	fmt.Printf("\n\tHealth Check Complete: %d passed, %d warnings, %d failed, %d skipped\n",
+		counts[health.StatusPass], counts[health.StatusWarn], counts[health.StatusFail], counts[health.StatusSkip])
 }
 
 func init() {
 	rootCmd.AddCommand(healthCmd)
-}
-
-// Here you will define your flags and configuration settings.
 
-// Cobra supports Persistent Flags which will work for this command
-// and all subcommands, e.g.:
-// healthCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-// Cobra supports local flags which will only run when this command
-// is called directly, e.g.:
-// healthCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	healthCmd.Flags().StringVarP(&healthOutputFlag, "output", "o", "text", "Output format: text or json.")
+}
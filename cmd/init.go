@@ -20,60 +20,65 @@ var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initializes a new configuration file with default values.",
 	Run: func(cmd *cobra.Command, args []string) {
-		configPath := config.ResolveConfigPath()
-		dbPath := config.ResolveDbPath()
-		_, confErr := os.Stat(configPath)
-		_, dbErr := os.Stat(dbPath)
-
-		if confErr == nil || dbErr == nil {
-			fmt.Fprintf(os.Stderr, "Error: Cannot run init. The following files already exist:\n")
-			if confErr == nil {
-				fmt.Fprintf(os.Stderr, "- Configuration file at %s\n", configPath)
-			}
-			if dbErr == nil {
-				fmt.Fprintf(os.Stderr, "- Database file at %s\n", dbPath)
-			}
-			fmt.Fprintf(os.Stderr, "Please remove the existing files before running 'init'.\n")
-			os.Exit(1)
-		} else {
-			if !os.IsNotExist(confErr) {
-				fmt.Fprintf(os.Stderr, "Error checking for config file at %s: %v\n", configPath, confErr)
-			}
-			if !os.IsNotExist(dbErr) {
-				fmt.Fprintf(os.Stderr, "Error checking for database file at %s: %v\n", dbPath, dbErr)
-			}
+		runInit()
+	},
+}
+
+// Creates the configuration and database of the active profile.
+func runInit() {
+	configPath := config.ResolveConfigPath()
+	dbPath := config.ResolveDbPath()
+	_, confErr := os.Stat(configPath)
+	_, dbErr := os.Stat(dbPath)
+
+	if confErr == nil || dbErr == nil {
+		fmt.Fprintf(os.Stderr, "Error: Cannot run init. The following files already exist:\n")
+		if confErr == nil {
+			fmt.Fprintf(os.Stderr, "- Configuration file at %s\n", configPath)
+		}
+		if dbErr == nil {
+			fmt.Fprintf(os.Stderr, "- Database file at %s\n", dbPath)
+		}
+		fmt.Fprintf(os.Stderr, "Please remove the existing files before running 'init'.\n")
+		os.Exit(1)
+	} else {
+		if !os.IsNotExist(confErr) {
+			fmt.Fprintf(os.Stderr, "Error checking for config file at %s: %v\n", configPath, confErr)
 		}
+		if !os.IsNotExist(dbErr) {
+			fmt.Fprintf(os.Stderr, "Error checking for database file at %s: %v\n", dbPath, dbErr)
+		}
+	}
 
-		var finalRepoPath string
-		var err error
+	var finalRepoPath string
+	var err error
 
-		if repoPathFlag != "" {
-			finalRepoPath, err = processRepoPath(repoPathFlag)
-		} else {
-			finalRepoPath, err = getRepoPathInteractive()
-		}
+	if repoPathFlag != "" {
+		finalRepoPath, err = processRepoPath(repoPathFlag)
+	} else {
+		finalRepoPath, err = getRepoPathInteractive()
+	}
 
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "\nInitialization aborted: %v\n", err)
-			os.Exit(1)
-		}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nInitialization aborted: %v\n", err)
+		os.Exit(1)
+	}
 
-		defaultConfig := config.GetMnemoConf()
-		defaultConfig.ConfigSchema.IsInit = true
-		defaultConfig.ConfigSchema.RepoPath = finalRepoPath
+	defaultConfig := config.GetMnemoConf()
+	defaultConfig.ConfigSchema.IsInit = true
+	defaultConfig.ConfigSchema.RepoPath = finalRepoPath
 
-		exists, _ := config.GitDirExists(finalRepoPath)
+	exists, _ := config.GitDirExists(finalRepoPath)
 
-		if exists {
-			fmt.Printf("\nRepository path validated: '%s/.git' exists.\n", finalRepoPath)
-			writeYAML(defaultConfig, configPath)
-			config.GetDataStore().SaveData(dbPath)
-			fmt.Printf("\nDatabase created at: '%s'.\n", dbPath)
-		} else {
-			fmt.Printf("\nDirectory '%s/.git' does not exist.\n", finalRepoPath)
-			fmt.Printf("Aborting configuration write.\n")
-		}
-	},
+	if exists {
+		fmt.Printf("\nRepository path validated: '%s/.git' exists.\n", finalRepoPath)
+		writeYAML(defaultConfig, configPath)
+		config.GetDataStore().SaveData(dbPath)
+		fmt.Printf("\nDatabase created at: '%s'.\n", dbPath)
+	} else {
+		fmt.Printf("\nDirectory '%s/.git' does not exist.\n", finalRepoPath)
+		fmt.Printf("Aborting configuration write.\n")
+	}
 }
 
 func init() {
@@ -181,7 +186,7 @@ func getRepoPathInteractive() (string, error) {
 	line.SetTabCompletionStyle(liner.TabPrints)
 	line.SetCtrlCAborts(true)
 
-	fmt.Println("Ensure that the target repository path is correct and does not contain other important files.\nDatabase for storing directories and their aliases would use the same parent directory.")
+	fmt.Println("Ensure that the target repository path is correct and does not contain other important files.\nThe database for storing directories and their aliases is kept separately, see mmsync config path --explain.")
 	for {
 		prompt := "Enter a valid path to the target repository to archive files to (e.g., /path/to/repo or ~/myrepo): "
 
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:         "list [selector...]",
+	Short:       "Lists the tracked directories",
+	Annotations: readOnlyCommand,
+	Long: `Lists the tracked directories with their stored path and the path it expands to on this machine.
+Paths inside the home directory are stored as ~/... or ${XDG_...}/... so the database and
+the repository manifests work across machines.
+
+` + selectorHelp + `
+
+Examples:
+
+mmsync list
+mmsync list tag:work notes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		if len(dataStore.TrackedDirs) == 0 {
+			fmt.Println("No directories are tracked. Add one with mmsync add <path>.")
+			return
+		}
+
+		ids := dataStore.IDs()
+		if len(args) > 0 {
+			ids = selectOrExit(args)
+		}
+
+		now := time.Now()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tALIAS\tREPO\tTAGS\tSTATUS\tSTORED PATH\tEXPANDED PATH")
+		for _, id := range ids {
+			entry := dataStore.TrackedDirs[id]
+
+			repoName := entry.Repo
+			if repo, err := appConf.RepositoryFor(entry); err == nil {
+				repoName = repo.Name
+			}
+
+			expanded, err := entry.Path()
+			if err != nil {
+				expanded = fmt.Sprintf("(%v)", err)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", id, entry.Alias, repoName, strings.Join(entry.Tags, ","), entry.PauseStatus(now), entry.TargetPath, expanded)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
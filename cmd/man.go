@@ -12,8 +12,9 @@ import (
 
 // manCmd represents the man command
 var manCmd = &cobra.Command{
-	Use:   "man",
-	Short: "Generates the manual page for mnemosync",
+	Use:         "man",
+	Short:       "Generates the manual page for mnemosync",
+	Annotations: readOnlyCommand,
 	Long: `Generates and displays manual page for mnemosync
 
 Does not persist it to a file.`,
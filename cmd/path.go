@@ -6,18 +6,27 @@ import (
 	"github.com/spf13/cobra"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"text/tabwriter"
 )
 
 var configCmd = &cobra.Command{
-	Use:   "config",
-	Short: "Manage the mnemosync configuration file",
-	Long:  "Provides commands to manage the application's configuration file.\nSet the configuration path either with $MMSYNC_CONF environment variable in your shell configuration file e.g. bashrc or with mmsync init.\nConfiguration directory should only be under your home directory.\nRestart your shell when changing or clearing the environment variable.",
+	Use:         "config",
+	Short:       "Manage the mnemosync configuration file",
+	Annotations: readOnlyCommand,
+	Long: `Provides commands to manage the application's configuration file.
+The configuration file is taken from, in order: the --config flag, the $MMSYNC_CONF environment variable,
+$XDG_CONFIG_HOME/mmsync/config.yaml and ~/.config/mmsync/config.yaml.
+The database is state rather than configuration and lives under $XDG_STATE_HOME/mmsync or ~/.local/state/mmsync,
+unless the configuration file was chosen with --config or $MMSYNC_CONF, in which case it is kept next to it.
+Run mmsync config path --explain to see how the current paths were chosen.
+Restart your shell when changing or clearing the environment variables.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		configPath := config.ResolveConfigPath()
 		isInit := appConf.ConfigSchema.IsInit
 
 		if !isInit {
-			fmt.Printf("\nConfiguration file not found at expected path\n%s\nRun mmsync init to start.\n", configPath)
+			fmt.Printf("\nConfiguration file not found at expected path\n%s\nRun %s to start.\n", configPath, initCommand())
 		} else {
 			fmt.Printf("\nConfiguration file path:\n%s\n", configPath)
 		}
@@ -25,18 +34,31 @@ var configCmd = &cobra.Command{
 }
 
 var getCmd = &cobra.Command{
-	Use:   "get",
-	Short: "Prints the current configuration to stdout",
-	Long:  "Prints the content of the mnemosync configuration file to the standard output. If the file doesn't exist, it prints a message.",
+	Use:         "get [key]",
+	Short:       "Prints the current configuration or a single value to stdout",
+	Annotations: readOnlyCommand,
+	Long: `Prints the content of the mnemosync configuration file to the standard output. If the file doesn't exist, it prints a message.
+When a key is given, only its value is printed, e.g. mmsync config get repo_path`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		configPath := config.ResolveConfigPath()
 		isInit := appConf.ConfigSchema.IsInit
 
 		if !isInit {
-			fmt.Printf("Error: Configuration file not found at expected path:\n%s\nRun mmsync init to start.\n", configPath)
+			fmt.Printf("Error: Configuration file not found at expected path:\n%s\nRun %s to start.\n", configPath, initCommand())
 			os.Exit(1)
 		}
 
+		if len(args) == 1 {
+			value, err := appConf.Get(args[0])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(value)
+			return
+		}
+
 		// Read and print the config file contents
 		content, err := os.ReadFile(configPath)
 		if err != nil {
@@ -62,7 +84,7 @@ var openCmd = &cobra.Command{
 		}
 
 		if !isInit {
-			fmt.Printf("\nConfiguration file not found at expected path\n%s\nRun mmsync init to start.\n", configPath)
+			fmt.Printf("\nConfiguration file not found at expected path\n%s\nRun %s to start.\n", configPath, initCommand())
 			os.Exit(1)
 		}
 
@@ -79,8 +101,267 @@ var openCmd = &cobra.Command{
 	},
 }
 
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Sets a single configuration value",
+	Long: `Sets a single configuration value by its key path, e.g. mmsync config set repo_path ~/backups
+The value is type checked and validated before the configuration file is saved.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath := config.ResolveConfigPath()
+
+		if !appConf.ConfigSchema.IsInit {
+			fmt.Printf("Error: Configuration file not found at expected path:\n%s\nRun %s to start.\n", configPath, initCommand())
+			os.Exit(1)
+		}
+
+		if err := appConf.Set(args[0], args[1]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		saveConfigOrExit(configPath)
+
+		value, _ := appConf.Get(args[0])
+		fmt.Printf("%s = %s\n", args[0], value)
+		warnEnvOverride(args[0])
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Resets a single configuration value to its default",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath := config.ResolveConfigPath()
+
+		if !appConf.ConfigSchema.IsInit {
+			fmt.Printf("Error: Configuration file not found at expected path:\n%s\nRun %s to start.\n", configPath, initCommand())
+			os.Exit(1)
+		}
+
+		if err := appConf.Unset(args[0]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		saveConfigOrExit(configPath)
+
+		value, _ := appConf.Get(args[0])
+		fmt.Printf("%s = %s\n", args[0], value)
+		warnEnvOverride(args[0])
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists every effective configuration value and where it came from",
+	Annotations: readOnlyCommand,
+	Long: `Lists every effective configuration value and where it came from: default, file, environment or flag.
+Every key can be overridden with an MMSYNC_ environment variable named after it, e.g. MMSYNC_REPO_PATH for repo_path.
+Entries of repos are overridden one at a time, e.g. MMSYNC_REPOS_TEAM for the repository named team.
+The name is matched regardless of case, so it also overrides a repository named Team.
+Overrides only apply in memory and are never written to the configuration file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, v := range appConf.List() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, v.Source)
+		}
+		w.Flush()
+	},
+}
+
+var explainFlag bool
+
+var configPathCmd = &cobra.Command{
+	Use:         "path",
+	Short:       "Prints the configuration and database paths of the active profile",
+	Annotations: readOnlyCommand,
+	Long:        "Prints the configuration and database paths of the active profile. With --explain, every location considered is shown in order of precedence.",
+	Run: func(cmd *cobra.Command, args []string) {
+		profile := config.ActiveProfile()
+
+		if !explainFlag {
+			fmt.Println(config.ResolveConfigPath())
+			fmt.Println(config.ResolveDbPath())
+			return
+		}
+
+		fmt.Printf("Profile: %s\n", profile)
+		if profile != config.DefaultProfile {
+			fmt.Printf("Named profiles live under %s in the directories below.\n", config.DefaultProfilesDir)
+		}
+
+		fmt.Println("\nConfiguration file (default profile):")
+		printCandidates(config.ExplainConfigPath())
+		fmt.Printf("  Resolved for profile '%s': %s\n", profile, config.ResolveConfigPath())
+
+		fmt.Println("\nDatabase file:")
+		printCandidates(config.ExplainDbPath(profile))
+	},
+}
+
+func printCandidates(candidates []config.PathCandidate) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, c := range candidates {
+		marker := " "
+		if c.Used {
+			marker = "*"
+		}
+		path := c.Path
+		if path == "" {
+			path = "(not set)"
+		}
+		note := ""
+		if c.Note != "" {
+			note = "(" + c.Note + ")"
+		}
+		fmt.Fprintf(w, "  %s %d.\t%s\t%s\t%s\n", marker, i+1, c.Source, path, note)
+	}
+	w.Flush()
+}
+
+var migrateFromFlag string
+var removeOldFlag bool
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Moves configuration and databases from an older location to the resolved ones",
+	Long: `Copies the configuration file and database of every profile from an older location to the paths mmsync now resolves.
+By default the old location is ~/.config/mmsync, where the database used to live next to the configuration file.
+Existing files at the destination are never overwritten. The old files are kept unless --remove-old is given.
+
+Examples:
+
+mmsync config migrate
+mmsync config migrate --from ~/old-mmsync --remove-old`,
+	Run: func(cmd *cobra.Command, args []string) {
+		from := migrateFromFlag
+		if from == "" {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				fmt.Printf("Error: failed to get home directory: %v\n", err)
+				os.Exit(1)
+			}
+			from = filepath.Join(homeDir, config.DefaultConfigDir)
+		}
+
+		from, err := config.ExpandPath(from)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		steps, err := config.MigrateConfigData(from, removeOldFlag)
+		for _, step := range steps {
+			fmt.Printf("- %s\n", step)
+		}
+		if err != nil {
+			fmt.Printf("Error: migration failed: %v\n", err)
+			os.Exit(1)
+		}
+		if len(steps) == 0 {
+			fmt.Println("Nothing to migrate.")
+			return
+		}
+		fmt.Println("Configuration migration complete.")
+	},
+}
+
+// Points out when a saved value will keep being overridden by the environment.
+func warnEnvOverride(key string) {
+	envVar := config.EnvVarFor(key)
+	if os.Getenv(envVar) != "" {
+		fmt.Printf("Note: $%s is set and overrides the saved value at runtime.\n", envVar)
+	}
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Reports every problem in a configuration file without changing it",
+	Long: `Reports every problem in a configuration file with its line and column, without healing or saving anything.
+Checks the YAML syntax, unknown keys, value types and the same rules used to heal the configuration on load.
+Validates the active profile's configuration file when no file is given. Exits with status 1 if any error is found.`,
+	Args: cobra.MaximumNArgs(1),
+	// Loading would heal the file, which is exactly what this command must not do
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		selectProfile()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		configPath := config.ResolveConfigPath()
+		if len(args) == 1 {
+			configPath = args[0]
+		}
+
+		issues, err := config.ValidateConfigFile(configPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		errorCount := 0
+		for _, issue := range issues {
+			fmt.Printf("%s:%s\n", configPath, issue)
+			if issue.Severity == config.SeverityError {
+				errorCount++
+			}
+		}
+
+		if errorCount > 0 {
+			fmt.Printf("\n%d error(s), %d warning(s)\n", errorCount, len(issues)-errorCount)
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid (%d warning(s))\n", configPath, len(issues))
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:       "schema [config|database]",
+	Short:     "Prints a JSON Schema for the configuration or database file",
+	ValidArgs: []string{"config", "database"},
+	Long: `Prints a JSON Schema for the configuration file, or for the database file with the database argument.
+Save it and point your editor at it for completion and linting, e.g. for yaml-language-server:
+
+mmsync config schema > ~/.config/mmsync/config.schema.json
+# yaml-language-server: $schema=./config.schema.json`,
+	Args: cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		selectProfile()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		generate := config.ConfigJSONSchema
+		if len(args) == 1 && args[0] == "database" {
+			generate = config.DataStoreJSONSchema
+		}
+
+		schema, err := generate()
+		if err != nil {
+			fmt.Printf("Error: failed to generate schema: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(schema))
+	},
+}
+
+func saveConfigOrExit(configPath string) {
+	if err := appConf.SaveConfig(configPath); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(getCmd)
 	configCmd.AddCommand(openCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
+
+	configPathCmd.Flags().BoolVar(&explainFlag, "explain", false, "Show every location considered, in order of precedence.")
+	configMigrateCmd.Flags().StringVar(&migrateFromFlag, "from", "", "Directory to migrate from (default is ~/.config/mmsync).")
+	configMigrateCmd.Flags().BoolVar(&removeOldFlag, "remove-old", false, "Remove the old files once copied.")
 }
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bladeacer/mmsync/config"
+	"github.com/spf13/cobra"
+)
+
+var pauseUntilFlag string
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause <selector>...",
+	Short: "Pauses the selected tracked directories",
+	Long: `Pauses the selected tracked directories. Paused entries keep their ID, alias and settings
+but are skipped until they are resumed, or until the date given with --until.
+
+` + selectorHelp + `
+
+Examples:
+
+mmsync pause photos
+mmsync pause tag:work --until 2026-01-05
+mmsync pause big-project --until 7d`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		now := time.Now()
+		var until *time.Time
+		if pauseUntilFlag != "" {
+			end, err := config.ParsePauseEnd(pauseUntilFlag, now)
+			if err != nil {
+				fmt.Printf("Error: invalid --until: %v\n", err)
+				os.Exit(1)
+			}
+			if !end.After(now) {
+				fmt.Printf("Error: --until %s is in the past\n", pauseUntilFlag)
+				os.Exit(1)
+			}
+			until = &end
+		}
+
+		setPaused(selectOrExit(args), true, until, now)
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <selector>...",
+	Short: "Resumes the selected paused directories",
+	Long: `Resumes the selected paused directories.
+
+` + selectorHelp,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		setPaused(selectOrExit(args), false, nil, time.Now())
+	},
+}
+
+func setPaused(ids []string, paused bool, until *time.Time, now time.Time) {
+	for _, id := range ids {
+		entry := dataStore.TrackedDirs[id]
+		entry.Paused = paused
+		entry.PausedUntil = until
+		dataStore.TrackedDirs[id] = entry
+
+		fmt.Printf("%s: %s\n", entry.Alias, entry.PauseStatus(now))
+	}
+
+	if err := saveDataStore(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+
+	pauseCmd.Flags().StringVarP(&pauseUntilFlag, "until", "u", "", "End of the pause: a date (2006-01-02), an RFC 3339 time or a duration such as 36h or 7d.")
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/bladeacer/mmsync/config"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var profileYesFlag bool
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named profiles",
+	Long: `Provides commands to manage named profiles.
+Each profile has its own configuration file, database and target repository.
+Select a profile for any command with --profile <name> or $MMSYNC_PROFILE.
+
+The default profile lives directly in the configuration directory, named profiles under its profiles/ directory.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists all profiles, marking the active one",
+	Annotations: readOnlyCommand,
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles, err := config.ListProfiles()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		active := config.ActiveProfile()
+		for _, name := range profiles {
+			marker := " "
+			if name == active {
+				marker = "*"
+			}
+			status := ""
+			if !config.ProfileExists(name) {
+				status = " (not initialized)"
+			}
+			fmt.Printf("%s %s%s\n", marker, name, status)
+		}
+	},
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Creates and initializes a new profile",
+	Long: `Creates a new profile and initializes its configuration, the same way mmsync init does for the active profile.
+
+Examples:
+
+mmsync profile create work --repo-path ~/work-backups
+mmsync --profile work add ~/notes`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		if err := config.ValidateProfileName(name); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if config.ProfileExists(name) {
+			fmt.Printf("Error: profile '%s' already exists.\n", name)
+			os.Exit(1)
+		}
+
+		config.SetProfile(name)
+		runInit()
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Deletes a profile's configuration and database",
+	Long:  "Deletes a profile's configuration and database. The profile's target repository is left untouched.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		if !profileYesFlag && !confirm(fmt.Sprintf("Delete profile '%s' and its database?", name)) {
+			fmt.Println("Aborted.")
+			return
+		}
+
+		if err := config.DeleteProfile(name); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted profile '%s'.\n", name)
+	},
+}
+
+var profileCopyCmd = &cobra.Command{
+	Use:   "copy <source> <destination>",
+	Short: "Copies a profile's configuration and database into a new profile",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := config.CopyProfile(args[0], args[1]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Copied profile '%s' to '%s'.\n", args[0], args[1])
+	},
+}
+
+// Shared by every prompt so that answers piped in ahead of time are not lost
+// to the buffer of an earlier one.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// Asks a yes/no question on stdin, defaulting to no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+
+	answer, err := stdinReader.ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+	profileCmd.AddCommand(profileCopyCmd)
+
+	profileCreateCmd.Flags().StringVarP(&repoPathFlag, "repo-path", "r", "", "Specify the path to the target Git repository.")
+	profileDeleteCmd.Flags().BoolVarP(&profileYesFlag, "yes", "y", false, "Delete without asking for confirmation.")
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bladeacer/mmsync/config"
+	"github.com/bladeacer/mmsync/syncer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	relocateRootsFlag []string
+	relocateDepthFlag int
+	relocateYesFlag   bool
+)
+
+// Share of matching files a candidate needs before --yes re-points an entry to it.
+const relocateMinSimilarity = 0.5
+
+var relocateCmd = &cobra.Command{
+	Use:   "relocate [selector...]",
+	Short: "Finds tracked directories that have moved and re-points them",
+	Long: `Finds tracked directories whose path no longer exists and searches for where they moved.
+Candidates are directories with the same name below the roots in relocate.roots (default ~),
+up to relocate.depth levels deep. They are ranked by how many files they share, by relative
+path and SHA-256, with the files recorded by the entry's last sync, or with its copy in the
+repository when it was never synced.
+
+For each entry the best candidate is offered. Re-pointing keeps the entry's ID and alias.
+With --yes the best candidate is taken without asking when it matches at least half of the
+files, and the entry is skipped otherwise, as it is when there are no files to compare with.
+
+Every entry is checked when no selector is given.
+
+` + selectorHelp + `
+
+Examples:
+
+mmsync relocate
+mmsync relocate notes --root ~/archive --depth 6
+mmsync relocate --yes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		ids := dataStore.IDs()
+		if len(args) > 0 {
+			ids = selectOrExit(args)
+		}
+
+		roots := appConf.RelocateRoots()
+		if len(relocateRootsFlag) > 0 {
+			roots = nil
+			for _, root := range relocateRootsFlag {
+				expanded, err := config.ExpandPath(root)
+				if err != nil {
+					fmt.Printf("Error: --root: %v\n", err)
+					os.Exit(1)
+				}
+				roots = append(roots, expanded)
+			}
+		}
+		depth := appConf.ConfigSchema.Relocate.Depth
+		if relocateDepthFlag > 0 {
+			depth = relocateDepthFlag
+		}
+
+		missing, relocated := 0, 0
+		for _, id := range ids {
+			entry := dataStore.TrackedDirs[id]
+			path, err := entry.Path()
+			if err != nil {
+				fmt.Printf("Skipping '%s' (ID: %s): %v\n", entry.Alias, id, err)
+				continue
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				continue
+			}
+
+			missing++
+			if relocateEntry(id, path, roots, depth) {
+				relocated++
+			}
+		}
+
+		if missing == 0 {
+			fmt.Println("Every tracked directory is where it was.")
+			return
+		}
+		if relocated > 0 {
+			if err := saveDataStore(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("\nRe-pointed %d of %d missing directories.\n", relocated, missing)
+	},
+}
+
+type relocateCandidate struct {
+	path string
+	// Share of files matching the repository copy, -1 when there is no copy to compare with
+	similarity float64
+}
+
+// Searches for the missing path of entry id and re-points the entry at the
+// chosen candidate in memory. Reports whether the entry changed.
+func relocateEntry(id string, missingPath string, roots []string, depth int) bool {
+	entry := dataStore.TrackedDirs[id]
+	fmt.Printf("\n'%s' (ID: %s): %s is missing\n", entry.Alias, id, missingPath)
+
+	paths := appConf.FindRelocated(dataStore, missingPath, roots, depth)
+	if len(paths) == 0 {
+		fmt.Printf("  No directory named '%s' found below %s.\n", filepath.Base(missingPath), strings.Join(roots, ", "))
+		return false
+	}
+
+	reference := syncedFingerprint(id, entry)
+
+	candidates := make([]relocateCandidate, len(paths))
+	for i, path := range paths {
+		candidates[i] = relocateCandidate{path: path, similarity: -1}
+		if len(reference) > 0 {
+			fp, _ := config.FingerprintDir(path)
+			candidates[i].similarity = reference.Similarity(fp)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  #\tMATCH\tPATH")
+	for i, c := range candidates {
+		match := "-"
+		if c.similarity >= 0 {
+			match = fmt.Sprintf("%.0f%%", c.similarity*100)
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\n", i+1, match, c.path)
+	}
+	w.Flush()
+
+	var chosen string
+	if relocateYesFlag {
+		best := candidates[0]
+		// An unknown similarity, -1, is below the threshold too
+		if best.similarity < relocateMinSimilarity {
+			fmt.Println("  No clear match, skipped. Re-run without --yes to choose one.")
+			return false
+		}
+		chosen = best.path
+	} else {
+		chosen = chooseCandidate(candidates)
+		if chosen == "" {
+			fmt.Println("  Skipped.")
+			return false
+		}
+	}
+
+	entry.TargetPath = config.PortablePath(chosen)
+	dataStore.TrackedDirs[id] = entry
+	fmt.Printf("  Re-pointed '%s' (ID: %s) to %s\n", entry.Alias, id, entry.TargetPath)
+	return true
+}
+
+// Fingerprints the files of an entry as of its last sync, from its sync
+// manifest, or from its copy in the repository when it has none.
+func syncedFingerprint(id string, entry config.DirData) config.Fingerprint {
+	manifest, err := syncer.LoadManifest(syncer.ManifestPath(syncer.StateDir(appConf), id))
+	if err == nil && manifest != nil && manifest.Alias == entry.Alias {
+		fp := make(config.Fingerprint, len(manifest.Files))
+		for path, state := range manifest.Files {
+			if state.Mode.IsRegular() {
+				fp[path] = state.SHA256
+			}
+		}
+		return fp
+	}
+
+	repo, err := appConf.RepositoryFor(entry)
+	if err != nil {
+		return nil
+	}
+	fp, _ := config.FingerprintDir(config.AliasDir(repo.Path, entry.Alias))
+	return fp
+}
+
+// Asks which candidate to use, defaulting to the best one. Returns an empty
+// string when the user skips the entry.
+func chooseCandidate(candidates []relocateCandidate) string {
+	for {
+		fmt.Printf("  Re-point to [1-%d], or s to skip [1]: ", len(candidates))
+
+		answer, err := stdinReader.ReadString('\n')
+		if err != nil && answer == "" {
+			return ""
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+
+		switch answer {
+		case "", "y", "yes":
+			return candidates[0].path
+		case "s", "skip", "n", "no":
+			return ""
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(candidates) {
+			return candidates[n-1].path
+		}
+		fmt.Printf("  Please answer a number from 1 to %d, or s.\n", len(candidates))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(relocateCmd)
+
+	relocateCmd.Flags().StringSliceVar(&relocateRootsFlag, "root", nil, "Directory to search instead of relocate.roots. Repeatable.")
+	relocateCmd.Flags().IntVar(&relocateDepthFlag, "depth", 0, "Levels below each root to search (default relocate.depth).")
+	relocateCmd.Flags().BoolVarP(&relocateYesFlag, "yes", "y", false, "Take the best candidate without asking when the match is clear.")
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var removeYesFlag bool
+
+var removeCmd = &cobra.Command{
+	Use:     "remove <selector>...",
+	Aliases: []string{"rm"},
+	Short:   "Stops tracking the selected directories",
+	Long: `Stops tracking the selected directories. The directories and their copies in the repository are left untouched.
+Asks for confirmation when more than one entry is selected, unless --yes is given.
+
+` + selectorHelp + `
+
+Examples:
+
+mmsync remove notes
+mmsync rm tag:old --yes`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		ids := selectOrExit(args)
+		if len(ids) > 1 && !removeYesFlag && !confirm(fmt.Sprintf("Stop tracking %d directories?", len(ids))) {
+			fmt.Println("Aborted.")
+			return
+		}
+
+		for _, id := range ids {
+			entry := dataStore.TrackedDirs[id]
+			delete(dataStore.TrackedDirs, id)
+			fmt.Printf("Removed '%s' (ID: %s, Path: %s)\n", entry.Alias, id, entry.TargetPath)
+		}
+
+		if err := saveDataStore(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+
+	removeCmd.Flags().BoolVarP(&removeYesFlag, "yes", "y", false, "Remove without asking for confirmation.")
+}
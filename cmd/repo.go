@@ -6,26 +6,40 @@ import (
 	"github.com/spf13/cobra"
 	"os"
 	"os/exec"
+	"text/tabwriter"
 )
 
 var repoCmd = &cobra.Command{
 	Use:   "repo",
 	Short: "Manage the Git repository path used for archiving",
-	Long:  "Provides commands to view and open the configured Git repository path.",
+	Long: `Provides commands to view and manage the configured Git repositories.
+The repository at repo_path is named 'default'. Additional named repositories can be added,
+and each tracked directory can be routed to one of them with mmsync add --repo <name>.`,
 }
 
 var repoGetCmd = &cobra.Command{
-	Use:   "get",
-	Short: "Prints the configured repository path to stdout",
+	Use:         "get [name]",
+	Short:       "Prints the configured repository path to stdout",
+	Annotations: readOnlyCommand,
+	Long:        "Prints the path of the named repository, or of the default repository when no name is given.",
+	Args:        cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		// Check if the config is initialized
 		if appConf == nil || !appConf.ConfigSchema.IsInit {
 			configPath := config.ResolveConfigPath()
-			fmt.Printf("Error: Configuration file not found or not initialized at expected path:\n%s\nRun mmsync init to start.\n", configPath)
+			fmt.Printf("Error: Configuration file not found or not initialized at expected path:\n%s\nRun %s to start.\n", configPath, initCommand())
 			os.Exit(1)
 		}
 
-		repoPath := appConf.ConfigSchema.RepoPath
+		repo := appConf.DefaultRepository()
+		if len(args) == 1 {
+			var err error
+			if repo, err = appConf.Repository(args[0]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		repoPath := repo.Path
 
 		if repoPath == "" {
 			fmt.Println("Error: Repository path is not set in the configuration file.")
@@ -50,7 +64,7 @@ var repoOpenCmd = &cobra.Command{
 		}
 
 		if !isInit {
-			fmt.Printf("\nConfiguration file not found at expected path\n%s\nRun mmsync init to start.\n", configPath)
+			fmt.Printf("\nConfiguration file not found at expected path\n%s\nRun %s to start.\n", configPath, initCommand())
 			os.Exit(1)
 		}
 
@@ -67,9 +81,100 @@ var repoOpenCmd = &cobra.Command{
 	},
 }
 
+var repoListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists the configured repositories, marking the default one",
+	Annotations: readOnlyCommand,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		defaultRepo := appConf.DefaultRepository().Name
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tNAME\tPATH\tTRACKED")
+		for _, repo := range appConf.Repositories() {
+			marker := ""
+			if repo.Name == defaultRepo {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", marker, repo.Name, repo.Path, len(trackedDirsIn(repo.Name)))
+		}
+		w.Flush()
+	},
+}
+
+var repoAddCmd = &cobra.Command{
+	Use:   "add <name> <path>",
+	Short: "Adds a named target repository",
+	Long: `Adds a named target repository. The path must already be a Git repository.
+
+Examples:
+
+mmsync repo add team ~/team-backups
+mmsync add ~/shared-notes --repo team`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		repoPath, err := processRepoPath(args[1])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := appConf.AddRepository(args[0], repoPath); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		saveConfigOrExit(config.ResolveConfigPath())
+
+		fmt.Printf("Added repository '%s' at %s\n", args[0], repoPath)
+	},
+}
+
+var repoRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Removes a named target repository from the configuration",
+	Long:  "Removes a named target repository from the configuration. The repository itself is left untouched.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		if err := appConf.RemoveRepository(args[0], dataStore); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		saveConfigOrExit(config.ResolveConfigPath())
+
+		fmt.Printf("Removed repository '%s'\n", args[0])
+	},
+}
+
+// Exits with a hint when the active profile is not initialized.
+func requireInit() {
+	if appConf == nil || !appConf.ConfigSchema.IsInit {
+		configPath := config.ResolveConfigPath()
+		fmt.Printf("Error: Configuration file not found or not initialized at expected path:\n%s\nRun %s to start.\n", configPath, initCommand())
+		os.Exit(1)
+	}
+}
+
+// Returns the IDs of tracked directories routed to the named repository.
+func trackedDirsIn(repoName string) []string {
+	var ids []string
+	for id, entry := range dataStore.TrackedDirs {
+		repo, err := appConf.RepositoryFor(entry)
+		if err == nil && repo.Name == repoName {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 func init() {
 	rootCmd.AddCommand(repoCmd)
 
 	repoCmd.AddCommand(repoGetCmd)
 	repoCmd.AddCommand(repoOpenCmd)
+	repoCmd.AddCommand(repoListCmd)
+	repoCmd.AddCommand(repoAddCmd)
+	repoCmd.AddCommand(repoRemoveCmd)
 }
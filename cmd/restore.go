@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bladeacer/mmsync/config"
+	"github.com/bladeacer/mmsync/syncer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreNoMetadataFlag bool
+	restoreSetuidFlag     bool
+)
+
+var restoreCmd = &cobra.Command{
+	Use:         "restore [selector...]",
+	Short:       "Copies tracked directories from their repositories back to their paths",
+	Annotations: readOnlyCommand,
+	Long: `Copies the <repo>/<alias> folder of each entry back to its path, e.g. on a new machine
+after mmsync bootstrap. Paths that already have content are left alone.
+
+Git only keeps the executable bit of a file. Every sync therefore also commits a sidecar,
+<repo>/.mmsync/metadata/<alias>.json, recording the mode, owner and group names and mtime
+of every file, the target of every symbolic link and every directory, empty ones included.
+With sync.xattrs set, user extended attributes are recorded as well. restore reapplies the
+sidecar after copying, recreating empty directories, unless --no-metadata is given.
+
+Owners are only changed where the restored file's differ, which usually takes root.
+Entries whose owner or extended attributes could not be set are counted and reported.
+
+The sidecar may come from a shared repository, so it is never trusted to reach outside
+the restored path: entries naming a path outside it, or reaching it through a symbolic
+link, are skipped. Setuid and setgid bits are dropped unless --setuid is given.
+
+Every entry is restored when no selector is given.
+
+` + selectorHelp + `
+
+Examples:
+
+mmsync restore
+mmsync restore dotfiles --no-metadata`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		ids := dataStore.IDs()
+		if len(args) > 0 {
+			ids = selectOrExit(args)
+		}
+		if len(ids) == 0 {
+			fmt.Println("Nothing to restore.")
+			return
+		}
+		restoreEntries(ids, !restoreNoMetadataFlag, restoreSetuidFlag)
+	},
+}
+
+// Copies the mirrored folder of each entry back to its target path and, with
+// metadata, reapplies its metadata sidecar, keeping setuid and setgid bits
+// only with setuid. Targets that already have content are left alone.
+func restoreEntries(ids []string, metadata bool, setuid bool) {
+	fmt.Println("\nRestoring entries:")
+	for _, id := range ids {
+		entry := dataStore.TrackedDirs[id]
+		target, err := entry.Path()
+		if err != nil {
+			fmt.Printf("  %s: failed: %v\n", entry.Alias, err)
+			continue
+		}
+		repo, err := appConf.RepositoryFor(entry)
+		if err != nil {
+			fmt.Printf("  %s: failed: %v\n", entry.Alias, err)
+			continue
+		}
+
+		src := config.AliasDir(repo.Path, entry.Alias)
+		var sidecar *syncer.Metadata
+		if metadata {
+			if sidecar, err = syncer.LoadMetadata(filepath.Join(repo.Path, config.AliasMetadataFile(entry.Alias))); err != nil {
+				fmt.Printf("  %s: failed: %v\n", entry.Alias, err)
+				continue
+			}
+		}
+
+		_, srcErr := os.Stat(src)
+		if srcErr != nil && sidecar == nil {
+			fmt.Printf("  %s: nothing to restore, %s does not exist\n", entry.Alias, src)
+			continue
+		}
+		if contents, err := os.ReadDir(target); err == nil && len(contents) > 0 {
+			fmt.Printf("  %s: skipped, %s is not empty\n", entry.Alias, target)
+			continue
+		}
+
+		// A folder holding only empty directories is not in git, only in the sidecar
+		if srcErr == nil {
+			err = config.RestoreDir(src, target)
+		} else {
+			err = os.MkdirAll(target, 0755)
+		}
+		if err != nil {
+			fmt.Printf("  %s: failed: %v\n", entry.Alias, err)
+			continue
+		}
+		if sidecar == nil {
+			fmt.Printf("  %s: restored to %s\n", entry.Alias, target)
+			continue
+		}
+
+		report, err := syncer.RestoreMetadata(target, sidecar, setuid)
+		if err != nil {
+			fmt.Printf("  %s: restored to %s, but failed to apply metadata: %v\n", entry.Alias, target, err)
+			continue
+		}
+		fmt.Printf("  %s: restored to %s with the metadata of %d entries\n", entry.Alias, target, report.Entries)
+		if report.OwnersSkipped > 0 {
+			fmt.Printf("  %s: Warning: could not set the owner of %d entries\n", entry.Alias, report.OwnersSkipped)
+		}
+		if report.XattrsSkipped > 0 {
+			fmt.Printf("  %s: Warning: could not set %d extended attributes\n", entry.Alias, report.XattrsSkipped)
+		}
+		if report.Skipped > 0 {
+			fmt.Printf("  %s: Warning: skipped %d entries outside %s or of another type than recorded\n", entry.Alias, report.Skipped, target)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().BoolVar(&restoreNoMetadataFlag, "no-metadata", false, "Only copy the files, without reapplying modes, owners, mtimes and empty directories.")
+	restoreCmd.Flags().BoolVar(&restoreSetuidFlag, "setuid", false, "Keep setuid and setgid bits recorded in the metadata sidecar.")
+}
@@ -10,6 +10,15 @@ import (
 var dataStore *config.DataStore
 var appConf *config.MnemoConf
 var versionFlag bool
+var profileFlag string
+var configFileFlag string
+var noHealFlag bool
+
+// Annotation marking commands that only read the configuration and database.
+// They are loaded without healing or saving anything.
+const readOnlyAnnotation = "mmsync.readonly"
+
+var readOnlyCommand = map[string]string{readOnlyAnnotation: "true"}
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -20,12 +29,17 @@ var rootCmd = &cobra.Command{
 The name is inspired by the Greek Goddess of memory Mnemosyne.
 
 This application assumes that you know how to create and set up a Git repository.`,
+	// Configuration is loaded here rather than in main so that global flags
+	// such as --profile are parsed before any path is resolved.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		loadProfile(cmd.Annotations[readOnlyAnnotation] == "true")
+	},
+	Annotations: readOnlyCommand,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	Run: func(cmd *cobra.Command, args []string) {
 		if versionFlag {
-			schema_ver := appConf.ConfigSchema.AppVersion
-			fmt.Printf("mnemosync %s\n", schema_ver)
+			fmt.Printf("mnemosync %s\n", config.AppVersion)
 			return
 		}
 		// Your original root command logic goes here
@@ -36,15 +50,66 @@ This application assumes that you know how to create and set up a Git repository
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
-func Execute(cfg *config.MnemoConf, data *config.DataStore) {
-	appConf = cfg
-	dataStore = data
+func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// Selects the configuration file and profile from the global flags and the
+// environment, then loads the profile's configuration and database.
+// Read-only loads report problems instead of healing them.
+func loadProfile(readOnly bool) {
+	selectProfile()
+	config.SetNoHeal(noHealFlag)
+
+	loadConfig, loadDataStore := config.LoadConfig, config.LoadDataStore
+	if readOnly {
+		loadConfig, loadDataStore = config.LoadConfigReadOnly, config.LoadDataStoreReadOnly
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := loadDataStore(cfg)
+	if err != nil {
+		fmt.Printf("Error loading database: %v\n", err)
+		os.Exit(1)
+	}
+
+	appConf = cfg
+	dataStore = data
+}
+
+// Selects the configuration file and profile without loading anything, for
+// commands that must work even when the configuration cannot be loaded.
+func selectProfile() {
+	if err := config.SetConfigFile(configFileFlag); err != nil {
+		fmt.Printf("Error: --config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := config.SetProfile(profileFlag); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := config.ValidateProfileName(config.ActiveProfile()); err != nil {
+		fmt.Printf("Error: $MMSYNC_PROFILE: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// Returns the init command line for the active profile, for use in hints.
+func initCommand() string {
+	if profile := config.ActiveProfile(); profile != config.DefaultProfile {
+		return fmt.Sprintf("mmsync --profile %s init", profile)
+	}
+	return "mmsync init"
+}
+
 func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
@@ -55,5 +120,8 @@ func init() {
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	// rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().StringVarP(&configFileFlag, "config", "c", "", "Configuration file to use (see mmsync config path --explain)")
+	rootCmd.PersistentFlags().BoolVar(&noHealFlag, "no-heal", false, "Report configuration and database problems instead of repairing them (see also auto_heal)")
+	rootCmd.PersistentFlags().StringVarP(&profileFlag, "profile", "p", "", "Profile to use (default is $MMSYNC_PROFILE or 'default')")
 	rootCmd.Flags().BoolVarP(&versionFlag, "version", "v", false, "Gets the version of mnemosync running")
 }
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bladeacer/mmsync/health"
+	"github.com/bladeacer/mmsync/syncer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncRehashFlag bool
+	syncJobsFlag   int
+	syncPlanFlag   string
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [selector...]",
+	Short: "Copies the tracked directories into their repositories and commits them",
+	Long: `Copies each tracked directory into the <repo>/<alias> folder of its repository and commits
+the folder, one commit per alias. Paused entries are skipped.
+
+Every sync records the files of an alias, with their size, mtime, mode and SHA-256, in a
+manifest in the state directory next to the database. The next sync only reads files whose
+size, mtime or mode changed, and reports a deleted and an added file with the same content
+as a rename. The counts of added, modified, deleted and renamed files go into the commit
+message and the journal at <state dir>/sync/journal.jsonl.
+
+Git only keeps the executable bit, so modes, owners, mtimes, symbolic link targets and
+directories, empty ones included, are committed in <repo>/.mmsync/metadata/<alias>.json
+for mmsync restore to reapply. Set sync.xattrs to record user extended attributes too.
+
+Up to sync.jobs directories (default 4), or --jobs, are synced at the same time. Commits
+to one repository are made one at a time. On a terminal a status line per running
+directory is shown, otherwise a line is printed as each directory finishes.
+
+Ctrl-C stops cleanly: files in the repository are replaced whole, never half-written, and
+a commit that has started is finished. Run mmsync sync again to sync what was left.
+
+Uncommitted changes in a <repo>/<alias> folder are discarded before it is synced. The
+manifest records the tree committed for the folder; when commits made by hand or pulled in
+changed it since, the copy in the repository is compared file by file instead. Use --rehash
+to ignore the manifest and compare the content of every file anyway.
+
+With --plan <file> nothing is copied or committed: the files to add, update, delete and
+rename for each alias are worked out, printed and saved to the file for review. mmsync
+apply <file> then carries out exactly that plan.
+
+Every entry is synced when no selector is given.
+
+` + selectorHelp + `
+
+Examples:
+
+mmsync sync
+mmsync sync tag:work --rehash
+mmsync sync --jobs 8
+mmsync sync tag:private --plan plan.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+		if err := health.RequireBinaries("git"); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		jobs := syncJobs(args)
+		if len(jobs) == 0 {
+			fmt.Println("Nothing to sync.")
+			return
+		}
+
+		ctx, stop := syncContext()
+		defer stop()
+
+		opts := syncer.Options{StateDir: syncer.StateDir(appConf), Rehash: syncRehashFlag, Xattrs: appConf.ConfigSchema.Sync.Xattrs}
+		if syncPlanFlag != "" {
+			writeSyncPlan(ctx, jobs, opts, syncPlanFlag)
+			return
+		}
+
+		display := newSyncDisplay(jobs)
+		opts.Progress = display.progress
+		results := syncer.RunAll(ctx, jobs, opts, syncWorkers(), display.done)
+		display.finish()
+		finishSync(jobs, results, opts.StateDir)
+	},
+}
+
+// Returns the number of directories synced at the same time.
+func syncWorkers() int {
+	if syncJobsFlag > 0 {
+		return syncJobsFlag
+	}
+	return appConf.ConfigSchema.Sync.Jobs
+}
+
+// Returns a context cancelled by the first Ctrl-C, which stops starting new
+// work and lets running copies finish their current file. A second one exits
+// at once.
+func syncContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// Records the results in the journal and exits non-zero when any directory
+// was interrupted or failed.
+func finishSync(jobs []syncer.Job, results []syncer.Result, stateDir string) {
+	var entries []syncer.JournalEntry
+	failed, cancelled := 0, 0
+	for _, result := range results {
+		entries = append(entries, syncer.NewJournalEntry(result, time.Now()))
+		switch {
+		case errors.Is(result.Err, context.Canceled):
+			cancelled++
+		case result.Err != nil:
+			failed++
+		}
+	}
+
+	if err := syncer.AppendJournal(syncer.JournalPath(stateDir), entries...); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if cancelled > 0 {
+		fmt.Printf("\nInterrupted, %d of %d directories were not synced. Run mmsync sync again to finish.\n", cancelled, len(jobs))
+		os.Exit(130)
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d directories failed to sync.\n", failed, len(jobs))
+		os.Exit(1)
+	}
+}
+
+// Builds the jobs for the selected entries, or all of them, skipping paused ones.
+func syncJobs(selectors []string) []syncer.Job {
+	ids := dataStore.IDs()
+	if len(selectors) > 0 {
+		ids = selectOrExit(selectors)
+	}
+
+	now := time.Now()
+	var jobs []syncer.Job
+	for _, id := range ids {
+		entry := dataStore.TrackedDirs[id]
+		if entry.IsPaused(now) {
+			fmt.Printf("Skipping '%s' (ID: %s): %s\n", entry.Alias, id, entry.PauseStatus(now))
+			continue
+		}
+
+		repo, err := appConf.RepositoryFor(entry)
+		if err != nil {
+			fmt.Printf("Error: '%s' (ID: %s): %v\n", entry.Alias, id, err)
+			os.Exit(1)
+		}
+		jobs = append(jobs, syncer.Job{ID: id, Entry: entry, Repo: repo})
+	}
+	return jobs
+}
+
+// Describes the outcome of syncing one directory in a line.
+func syncResultLine(r syncer.Result) string {
+	name := fmt.Sprintf("%s (ID: %s)", r.Job.Entry.Alias, r.Job.ID)
+	switch {
+	case errors.Is(r.Err, context.Canceled):
+		return fmt.Sprintf("%s: interrupted", name)
+	case r.Err != nil:
+		return fmt.Sprintf("%s: Error: %v", name, r.Err)
+	case r.Commit != "":
+		return fmt.Sprintf("%s: %s, committed %s", name, r.Changes.Summary(), r.Commit)
+	case !r.Changes.Empty():
+		return fmt.Sprintf("%s: %s in the repository copy, which now matches the last commit", name, r.Changes.Summary())
+	default:
+		return fmt.Sprintf("%s: up to date", name)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().IntVarP(&syncJobsFlag, "jobs", "j", 0, "Directories synced at the same time (default sync.jobs).")
+	syncCmd.Flags().StringVar(&syncPlanFlag, "plan", "", "Write what the sync would do to this file instead of syncing; carry it out with mmsync apply.")
+	syncCmd.Flags().BoolVar(&syncRehashFlag, "rehash", false, "Compare the content of every file instead of trusting the manifest.")
+}
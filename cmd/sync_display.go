@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bladeacer/mmsync/syncer"
+)
+
+// How often the live display is redrawn.
+const syncRedrawInterval = 100 * time.Millisecond
+
+// syncDisplay shows the progress and results of a sync run.
+type syncDisplay interface {
+	progress(p syncer.Progress)
+	done(r syncer.Result)
+	finish()
+}
+
+// Returns a live display when stdout is a terminal, plain lines otherwise.
+func newSyncDisplay(jobs []syncer.Job) syncDisplay {
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 || os.Getenv("TERM") == "dumb" {
+		return plainSyncDisplay{}
+	}
+	return newLiveSyncDisplay(jobs)
+}
+
+// Prints one line per finished directory.
+type plainSyncDisplay struct{}
+
+func (plainSyncDisplay) progress(p syncer.Progress) {}
+func (plainSyncDisplay) done(r syncer.Result)       { fmt.Println(syncResultLine(r)) }
+func (plainSyncDisplay) finish()                    {}
+
+// Keeps one status line per running directory at the bottom of the terminal.
+// Finished directories are printed above it in full.
+type liveSyncDisplay struct {
+	mu       sync.Mutex
+	jobs     []syncer.Job
+	active   map[string]string
+	finished []string
+	// Lines of the status area currently on screen
+	drawn int
+	width int
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+func newLiveSyncDisplay(jobs []syncer.Job) *liveSyncDisplay {
+	d := &liveSyncDisplay{
+		jobs:   jobs,
+		active: make(map[string]string),
+		width:  80,
+		stop:   make(chan struct{}),
+	}
+	if columns := terminalWidth(os.Stdout); columns > 0 {
+		d.width = columns
+	} else if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		d.width = columns
+	}
+
+	d.stopped.Add(1)
+	go func() {
+		defer d.stopped.Done()
+		ticker := time.NewTicker(syncRedrawInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.mu.Lock()
+				d.draw()
+				d.mu.Unlock()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+	return d
+}
+
+func (d *liveSyncDisplay) progress(p syncer.Progress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := p.Stage
+	switch {
+	case p.Stage == syncer.StageCopying:
+		status = fmt.Sprintf("%s %d/%d", p.Stage, p.Done, p.Total)
+	case p.Stage == syncer.StageScanning && p.Done > 0:
+		status = fmt.Sprintf("%s, %d files", p.Stage, p.Done)
+	}
+	d.active[p.Job.ID] = fmt.Sprintf("%s (ID: %s): %s", p.Job.Entry.Alias, p.Job.ID, status)
+}
+
+func (d *liveSyncDisplay) done(r syncer.Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.active, r.Job.ID)
+	d.finished = append(d.finished, syncResultLine(r))
+}
+
+func (d *liveSyncDisplay) finish() {
+	close(d.stop)
+	d.stopped.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.active = make(map[string]string)
+	d.draw()
+}
+
+// Replaces the status area with any newly finished lines followed by the
+// current status lines. Must be called with mu held.
+func (d *liveSyncDisplay) draw() {
+	if d.drawn > 0 {
+		fmt.Printf("\033[%dA\033[J", d.drawn)
+	}
+	for _, line := range d.finished {
+		fmt.Println(line)
+	}
+	d.finished = nil
+
+	d.drawn = 0
+	for _, job := range d.jobs {
+		if line, ok := d.active[job.ID]; ok {
+			if runes := []rune(line); len(runes) >= d.width {
+				line = string(runes[:d.width-1])
+			}
+			fmt.Println(line)
+			d.drawn++
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bladeacer/mmsync/config"
+	"github.com/spf13/cobra"
+)
+
+// Shared by every command taking selectors.
+const selectorHelp = `A selector is an ID, an alias, tag:<name>, or a glob matched against the stored
+and the expanded path, e.g. '~/projects/*'. Several selectors select every entry matching any of them.`
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Manage the tags of tracked directories",
+	Long: `Provides commands to tag tracked directories, so groups of them can be selected with tag:<name>.
+
+` + selectorHelp,
+}
+
+var tagListCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "Lists every tag with the number of entries carrying it",
+	Annotations: readOnlyCommand,
+	Args:        cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		counts := make(map[string]int)
+		for _, entry := range dataStore.TrackedDirs {
+			for _, tag := range entry.Tags {
+				counts[tag]++
+			}
+		}
+		if len(counts) == 0 {
+			fmt.Println("No tags are set. Add one with mmsync tag add <tag> <selector>.")
+			return
+		}
+
+		tags := make([]string, 0, len(counts))
+		for tag := range counts {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TAG\tENTRIES")
+		for _, tag := range tags {
+			fmt.Fprintf(w, "%s\t%d\n", tag, counts[tag])
+		}
+		w.Flush()
+	},
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <tag> <selector>...",
+	Short: "Adds a tag to the selected entries",
+	Long: `Adds a tag to the selected entries.
+
+` + selectorHelp + `
+
+Examples:
+
+mmsync tag add work notes
+mmsync tag add dotfiles '~/.config/*'`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		changeTag(args[0], args[1:], (*config.DirData).AddTag)
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove <tag> [selector...]",
+	Short: "Removes a tag from the selected entries, or from every entry",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		selectors := args[1:]
+		if len(selectors) == 0 {
+			selectors = []string{config.TagPrefix + args[0]}
+		}
+		changeTag(args[0], selectors, (*config.DirData).RemoveTag)
+	},
+}
+
+// Applies change to the selected entries and saves when any of them changed.
+func changeTag(tag string, selectors []string, change func(*config.DirData, string) bool) {
+	requireInit()
+
+	if err := config.ValidateTag(tag); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var changed []string
+	for _, id := range selectOrExit(selectors) {
+		entry := dataStore.TrackedDirs[id]
+		if change(&entry, tag) {
+			dataStore.TrackedDirs[id] = entry
+			changed = append(changed, entry.Alias)
+		}
+	}
+
+	if len(changed) == 0 {
+		fmt.Println("No entries changed.")
+		return
+	}
+	if err := saveDataStore(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Updated tag '%s' on: %s\n", tag, strings.Join(changed, ", "))
+}
+
+// Resolves selectors to entry IDs, exiting when one matches nothing.
+func selectOrExit(selectors []string) []string {
+	ids, err := dataStore.Select(selectors)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return ids
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+
+	tagCmd.AddCommand(tagListCmd)
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+}
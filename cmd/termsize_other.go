@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package cmd
+
+import "os"
+
+func terminalWidth(f *os.File) int { return 0 }
@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Returns the width of the terminal on f, or 0 when it is not a terminal.
+func terminalWidth(f *os.File) int {
+	var size struct{ rows, cols, xpixel, ypixel uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0
+	}
+	return int(size.cols)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bladeacer/mmsync/config"
+	"github.com/spf13/cobra"
+)
+
+var exportFormatFlag string
+var importFormatFlag string
+var importModeFlag string
+var importDryRunFlag bool
+
+var exportCmd = &cobra.Command{
+	Use:         "export",
+	Short:       "Writes the tracked directories to stdout",
+	Annotations: readOnlyCommand,
+	Long: `Writes every tracked directory with its ID, alias, stored path and repository to stdout.
+The output can be read back with mmsync import.
+
+Examples:
+
+mmsync export > tracked.json
+mmsync export --format csv > tracked.csv`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		if err := config.EncodeEntries(os.Stdout, dataStore.ExportEntries(), exportFormatFlag); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file|->",
+	Short: "Tracks the directories listed in an export",
+	Long: `Tracks the directories listed in a file written by mmsync export, or read from stdin with -.
+The format is taken from --format, then the file extension, and defaults to json.
+
+In merge mode entries are added next to the existing ones. In replace mode the existing
+entries are dropped first. Entries whose alias or path is already tracked, or whose repository
+is not configured, are reported and skipped. IDs from the file are kept when they are free.
+
+Examples:
+
+mmsync import tracked.json --dry-run
+mmsync export | ssh laptop mmsync import - --mode replace`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireInit()
+
+		if importModeFlag != "merge" && importModeFlag != "replace" {
+			fmt.Printf("Error: unknown mode '%s', expected merge or replace\n", importModeFlag)
+			os.Exit(1)
+		}
+
+		entries, err := readImport(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if importModeFlag == "replace" {
+			dataStore.TrackedDirs = make(map[string]config.DirData)
+		}
+
+		added, skipped := importEntries(entries)
+
+		fmt.Printf("\n%d entries imported, %d skipped.\n", added, skipped)
+		if importDryRunFlag {
+			fmt.Println("Dry run: the database was not changed.")
+			return
+		}
+		if err := saveDataStore(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func readImport(source string) ([]config.ExportEntry, error) {
+	format := importFormatFlag
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(source)) {
+		case ".yaml", ".yml":
+			format = "yaml"
+		case ".csv":
+			format = "csv"
+		default:
+			format = "json"
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if source != "-" {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("error opening import file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	return config.DecodeEntries(r, format)
+}
+
+// Adds each entry that does not conflict, printing the result of every one.
+func importEntries(entries []config.ExportEntry) (int, int) {
+	added, skipped := 0, 0
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ALIAS\tPATH\tRESULT")
+	for _, e := range entries {
+		entry := e.DirData
+
+		path, err := entry.Path()
+		if err == nil {
+			_, err = appConf.RepositoryFor(entry)
+		}
+		if err == nil {
+			err = checkEntryConflict(path, entry.Alias)
+		}
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%s\tskipped: %v\n", entry.Alias, entry.TargetPath, err)
+			skipped++
+			continue
+		}
+
+		if filepath.IsAbs(entry.TargetPath) {
+			entry.TargetPath = config.PortablePath(entry.TargetPath)
+		}
+		id := insertEntry(e.ID, entry)
+		fmt.Fprintf(w, "%s\t%s\tadded (ID: %s)\n", entry.Alias, entry.TargetPath, id)
+		added++
+	}
+	w.Flush()
+
+	return added, skipped
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+
+	exportCmd.Flags().StringVarP(&exportFormatFlag, "format", "f", "json", "Output format: "+strings.Join(config.ExportFormats, ", ")+".")
+	importCmd.Flags().StringVarP(&importFormatFlag, "format", "f", "", "Input format: "+strings.Join(config.ExportFormats, ", ")+" (default from the file extension, else json).")
+	importCmd.Flags().StringVarP(&importModeFlag, "mode", "m", "merge", "merge adds to the tracked directories, replace drops them first.")
+	importCmd.Flags().BoolVar(&importDryRunFlag, "dry-run", false, "Report what would be imported without saving.")
+}
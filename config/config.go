@@ -6,28 +6,100 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
+// AppVersion is the version of the mmsync binary. It is recorded in the
+// configuration file on every save but is independent of SchemaVersion.
+const AppVersion = "Version 0.0.1"
+
 type ConfigSchema struct {
-	ConfigPath string `yaml:"config_path"`
-	AppVersion string `yaml:"app_version"`
-	IsInit     bool   `yaml:"is_init"`
-	RepoPath   string `yaml:"repo_path"`
-	DbPath     string `yaml:"db_path"`
+	SchemaVersion int    `yaml:"schema_version" mmsync:"readonly,noenv"`
+	ConfigPath    string `yaml:"config_path" mmsync:"path,readonly,noenv"`
+	AppVersion    string `yaml:"app_version" mmsync:"readonly,noenv"`
+	IsInit        bool   `yaml:"is_init" mmsync:"readonly"`
+	RepoPath      string `yaml:"repo_path" mmsync:"path"`
+	DbPath        string `yaml:"db_path" mmsync:"path,readonly"`
+
+	// Additional named target repositories, managed with `mmsync repo add/remove`
+	Repos       map[string]string `yaml:"repos,omitempty" mmsync:"path,readonly"`
+	DefaultRepo string            `yaml:"default_repo,omitempty"`
+
+	// Repair invalid values on load and save them back
+	AutoHeal bool `yaml:"auto_heal"`
+
+	// Paths refused by add
+	Deny DenyRules `yaml:"deny"`
+
+	Health HealthOptions `yaml:"health"`
+
+	Relocate RelocateOptions `yaml:"relocate"`
+
+	Clean CleanOptions `yaml:"clean"`
+
+	Sync SyncOptions `yaml:"sync"`
+}
+
+// HealthOptions tunes the checks run by `mmsync health`.
+type HealthOptions struct {
+	// Days without a commit touching an alias before it is reported as stale, 0 disables the check
+	StaleDays int `yaml:"stale_days"`
+}
+
+// RelocateOptions tunes where `mmsync relocate` looks for moved directories.
+type RelocateOptions struct {
+	// Directories searched for tracked directories that have moved. A ~, $HOME or $XDG_... prefix is expanded.
+	Roots []string `yaml:"roots"`
+	// Levels below each root that are searched
+	Depth int `yaml:"depth"`
+}
+
+// CleanOptions tunes what `mmsync clean` reports as orphaned.
+type CleanOptions struct {
+	// Globs of top-level repository entries that are never orphans
+	Keep []string `yaml:"keep"`
+}
+
+// SyncOptions tunes `mmsync sync`.
+type SyncOptions struct {
+	// Tracked directories synced at the same time
+	Jobs int `yaml:"jobs"`
+	// Record the user extended attributes of files in the metadata sidecar
+	Xattrs bool `yaml:"xattrs"`
 }
 
 type MnemoConf struct {
 	ConfigSchema ConfigSchema `yaml:"config_schema"`
+
+	// Where each effective value came from, keyed by dotted key path
+	sources map[string]ValueSource
+	// Values read from the file for keys overridden by the environment,
+	// written back in their place by saveConfig
+	fileValues map[string]reflect.Value
+	// Map entries overridden by the environment, by key and entry name
+	mapOverrides map[string]map[string]mapOverride
 }
 
 func GetMnemoConf() *MnemoConf {
 	return &MnemoConf{
-		ConfigSchema{
-			ConfigPath: ResolveConfigPath(),
-			AppVersion: "Version 0.0.1",
-			IsInit:     false,
-			RepoPath:   "",
-			DbPath:     ResolveDbPath(),
+		sources:      make(map[string]ValueSource),
+		fileValues:   make(map[string]reflect.Value),
+		mapOverrides: make(map[string]map[string]mapOverride),
+		ConfigSchema: ConfigSchema{
+			SchemaVersion: CurrentSchemaVersion,
+			ConfigPath:    ResolveConfigPath(),
+			AppVersion:    AppVersion,
+			IsInit:        false,
+			RepoPath:      "",
+			DbPath:        ResolveDbPath(),
+			AutoHeal:      true,
+			Deny:          defaultDenyRules(),
+			Health:        HealthOptions{StaleDays: 30},
+			Relocate:      RelocateOptions{Roots: []string{"~"}, Depth: 4},
+			Sync:          SyncOptions{Jobs: 4},
+			Clean:         CleanOptions{Keep: []string{".*", "README*", "LICENSE*", "COPYING*", "CHANGELOG*", "Makefile"}},
 		},
 	}
 }
@@ -38,43 +110,33 @@ const (
 	DefaultDbFile     = "mmsync-state.json"
 )
 
+// ResolveConfigPath returns the configuration file of the active profile.
+// See ExplainConfigPath for the order of precedence.
 func ResolveConfigPath() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return filepath.Join(DefaultConfigDir, DefaultConfigFile)
-	}
-
-	if envPath := os.Getenv("MMSYNC_CONF"); envPath != "" {
-		resolvedPath := envPath
+	return ProfileConfigPath(ActiveProfile())
+}
 
-		if !filepath.IsAbs(envPath) {
-			resolvedPath = filepath.Join(homeDir, envPath)
-		}
+// ResolveDbPath returns the database file of the active profile.
+// See ExplainDbPath for the order of precedence.
+func ResolveDbPath() string {
+	return ProfileDbPath(ActiveProfile())
+}
 
-		if filepath.Base(resolvedPath) != DefaultConfigFile {
-			resolvedPath = filepath.Join(resolvedPath, DefaultConfigFile)
+// ExpandPath expands a leading ~ to the user's home directory and returns an absolute path.
+func ExpandPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~"+string(os.PathSeparator)) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory for tilde expansion: %w", err)
 		}
-
-		return resolvedPath
+		path = filepath.Join(homeDir, path[1:])
 	}
 
-	return filepath.Join(homeDir, DefaultConfigDir, DefaultConfigFile)
-}
-
-func ResolveDbPath() string {
-	homeDir, err := os.UserHomeDir()
+	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return filepath.Join(DefaultConfigDir, DefaultDbFile)
+		return "", fmt.Errorf("failed to resolve absolute path for '%s': %w", path, err)
 	}
-
-	if envPath := os.Getenv("MMSYNC_CONF"); envPath != "" {
-		configPath := ResolveConfigPath()
-
-		configDir := filepath.Dir(configPath)
-		return filepath.Join(configDir, DefaultDbFile)
-	}
-
-	return filepath.Join(homeDir, DefaultConfigDir, DefaultDbFile)
+	return absPath, nil
 }
 
 func copyFile(src, dst string) error {
@@ -105,61 +167,56 @@ func copyFile(src, dst string) error {
 	return nil
 }
 
-// Copies configuration and database files when new MMSYNC_CONF set
-func migrateConfigData(newConfigPath string) error {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil
-	}
-
-	oldConfigDir := filepath.Join(homeDir, DefaultConfigDir)
-	oldConfigFile := filepath.Join(oldConfigDir, DefaultConfigFile)
-	oldDbFile := filepath.Join(oldConfigDir, DefaultDbFile)
+// Set with --no-heal. Takes precedence over the auto_heal option.
+var noHeal bool
 
-	newConfigDir := filepath.Dir(newConfigPath)
-	newDbFile := filepath.Join(newConfigDir, DefaultDbFile)
-
-	if os.Getenv("MMSYNC_CONF") != "" && oldConfigDir != newConfigDir {
-
-		if _, err := os.Stat(oldConfigFile); os.IsNotExist(err) {
-			return nil
-		} else if err != nil {
-			return fmt.Errorf("error checking old configuration file %s: %w", oldConfigFile, err)
-		}
+// SetNoHeal disables automatic repair of the configuration and database for this run.
+func SetNoHeal(disabled bool) {
+	noHeal = disabled
+}
 
-		if _, err := os.Stat(newConfigPath); err == nil {
-			return fmt.Errorf("cannot migrate: new configuration file already exists at %s", newConfigPath)
-		}
+// LoadConfig loads the configuration of the active profile, healing and saving
+// the file if needed, then applies MMSYNC_* environment overrides in memory.
+func LoadConfig() (*MnemoConf, error) {
+	return loadConfig(false)
+}
 
-		fmt.Fprintf(os.Stderr, "Migrating configuration files from %s to %s...\n", oldConfigDir, newConfigDir)
+// LoadConfigReadOnly loads the configuration of the active profile without
+// migrating, healing or saving anything. Problems are only reported, along with
+// what healing would do.
+func LoadConfigReadOnly() (*MnemoConf, error) {
+	return loadConfig(true)
+}
 
-		if err := copyFile(oldConfigFile, newConfigPath); err != nil {
-			return fmt.Errorf("failed to copy configuration file: %w", err)
-		}
+func loadConfig(readOnly bool) (*MnemoConf, error) {
+	cfg, err := loadConfigFile(ResolveConfigPath(), readOnly)
+	if err != nil {
+		return nil, err
+	}
 
-		if _, err := os.Stat(oldDbFile); err == nil {
-			if err := copyFile(oldDbFile, newDbFile); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to copy database file: %v\n", err)
-			}
-		}
+	if err := cfg.applyEnvOverrides(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-		if err := os.RemoveAll(oldConfigDir); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to clean up old configuration directory %s: %v\n", oldConfigDir, err)
+// Reports whether automatic healing is enabled by auto_heal, its environment
+// override and --no-heal.
+func autoHealEnabled(cfg *MnemoConf) bool {
+	if noHeal {
+		return false
+	}
+	if raw := os.Getenv(EnvVarFor("auto_heal")); raw != "" {
+		if enabled, err := strconv.ParseBool(raw); err == nil {
+			return enabled
 		}
-
-		fmt.Fprintf(os.Stderr, "Configuration migration complete.\n")
 	}
-
-	return nil
+	return cfg.ConfigSchema.AutoHeal
 }
 
-func LoadConfig() (*MnemoConf, error) {
-	configPath := ResolveConfigPath()
-
-	if err := migrateConfigData(configPath); err != nil {
-		return nil, fmt.Errorf("Configuration migration failed: %w", err)
-	}
+func loadConfigFile(configPath string, readOnly bool) (*MnemoConf, error) {
 	defaultCfg := GetMnemoConf()
+	defaultCfg.markPathSources()
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -169,21 +226,83 @@ func LoadConfig() (*MnemoConf, error) {
 		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
 
+	migratedData, fromVersion, err := migrateConfigSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("Configuration migration failed: %w", err)
+	}
+	migrated := fromVersion != CurrentSchemaVersion
+
 	tempCfg := GetMnemoConf()
 
-	if err := yaml.Unmarshal(data, tempCfg); err != nil {
+	if err := yaml.Unmarshal(migratedData, tempCfg); err != nil {
 		return nil, fmt.Errorf("error unmarshalling YAML data. File may be invalid: %w", err)
 	}
 
-	warnings := healConfigSchema(tempCfg, defaultCfg)
+	for key := range fileKeys(migratedData) {
+		tempCfg.sources[key] = SourceFile
+	}
+
+	// The stored paths are informational; the resolved ones are what is actually used
+	tempCfg.ConfigSchema.ConfigPath = configPath
+	tempCfg.ConfigSchema.DbPath = ResolveDbPath()
+	tempCfg.markPathSources()
+
+	heal := !readOnly && autoHealEnabled(tempCfg)
+
+	// Without healing, repairs are worked out on a throwaway copy and only reported
+	target := tempCfg
+	if !heal {
+		target = GetMnemoConf()
+		if err := yaml.Unmarshal(migratedData, target); err != nil {
+			return nil, fmt.Errorf("error unmarshalling YAML data. File may be invalid: %w", err)
+		}
+		target.ConfigSchema.ConfigPath = tempCfg.ConfigSchema.ConfigPath
+		target.ConfigSchema.DbPath = tempCfg.ConfigSchema.DbPath
+	}
+	repairs, notices := healConfigSchema(target, defaultCfg)
+
+	for _, n := range notices {
+		fmt.Fprintf(os.Stderr, "Config Warning: %v\n", n)
+	}
 
-	if len(warnings) > 0 {
+	if !heal {
+		if migrated {
+			fmt.Fprintf(os.Stderr, "Config Warning: configuration uses schema version %d and will be migrated to %d once healing is allowed.\n", fromVersion, CurrentSchemaVersion)
+		}
+		if len(repairs) > 0 {
+			fmt.Fprintf(os.Stderr, "--- Configuration Healing Needed (not applied) ---\n")
+			for _, w := range repairs {
+				fmt.Fprintf(os.Stderr, "Config Warning: %v\n", w)
+			}
+			fmt.Fprintf(os.Stderr, "--- Healing is disabled for this command; run mmsync config validate for details ---\n\n")
+		}
+		return tempCfg, nil
+	}
+
+	if len(repairs) > 0 {
 		fmt.Fprintf(os.Stderr, "--- Configuration Healing Performed ---\n")
-		for _, w := range warnings {
+		for _, w := range repairs {
 			fmt.Fprintf(os.Stderr, "Config Warning: %v\n", w)
 		}
 		fmt.Fprintf(os.Stderr, "--- Saving Repaired Configuration ---\n\n")
+	}
+
+	if migrated {
+		fmt.Fprintf(os.Stderr, "Migrated configuration from schema version %d to %d.\n", fromVersion, CurrentSchemaVersion)
+		backupPath := fmt.Sprintf("%s.v%d.bak", configPath, fromVersion)
+		if err := copyFile(configPath, backupPath); err != nil {
+			return nil, fmt.Errorf("failed to back up configuration before migration: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Previous configuration backed up to %s\n", backupPath)
+	}
+	if len(repairs) > 0 {
+		if err := copyFile(configPath, configPath+".bak"); err != nil {
+			return nil, fmt.Errorf("failed to back up configuration before healing: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Previous configuration backed up to %s.bak\n", configPath)
+	}
 
+	if len(repairs) > 0 || migrated {
 		if saveErr := saveConfig(tempCfg, configPath); saveErr != nil {
 			return nil, fmt.Errorf("critical error: failed to save repaired configuration: %w", saveErr)
 		}
@@ -192,22 +311,80 @@ func LoadConfig() (*MnemoConf, error) {
 	return tempCfg, nil
 }
 
+// Records where the resolved config_path and db_path came from.
+func (cfg *MnemoConf) markPathSources() {
+	switch configPathSource().Source {
+	case "--config flag":
+		cfg.sources["config_path"] = SourceFlag
+		cfg.sources["db_path"] = SourceFlag
+	case "$MMSYNC_CONF", "$XDG_CONFIG_HOME":
+		cfg.sources["config_path"] = SourceEnvironment
+		cfg.sources["db_path"] = SourceEnvironment
+	}
+	if filepath.IsAbs(os.Getenv("XDG_STATE_HOME")) && !explicitConfigFile() {
+		cfg.sources["db_path"] = SourceEnvironment
+	}
+}
+
+// SaveConfig atomically writes the configuration to targetPath.
+func (cfg *MnemoConf) SaveConfig(targetPath string) error {
+	return saveConfig(cfg, targetPath)
+}
+
 func saveConfig(cfg *MnemoConf, targetPath string) error {
-	jsonData, err := yaml.Marshal(cfg)
+	jsonData, err := marshalConfig(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal MnemoConf to YAML: %w", err)
+		return err
 	}
 
 	dir := filepath.Dir(targetPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory structure for %s: %w", targetPath, err)
 	}
-	if err := os.WriteFile(targetPath, jsonData, 0644); err != nil {
+	if err := writeFileAtomic(targetPath, jsonData, 0644); err != nil {
 		return fmt.Errorf("failed to write YAML data to file %s: %w", targetPath, err)
 	}
 	return nil
 }
 
+// Returns the YAML saveConfig writes for cfg.
+func marshalConfig(cfg *MnemoConf) ([]byte, error) {
+	cfg.ConfigSchema.AppVersion = AppVersion
+
+	data, err := yaml.Marshal(cfg.withoutEnvOverrides())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal MnemoConf to YAML: %w", err)
+	}
+	return data, nil
+}
+
+// Writes data to a temporary file in the same directory and renames it over
+// targetPath, so readers never observe a partially written file.
+func writeFileAtomic(targetPath string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(targetPath), "."+filepath.Base(targetPath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, targetPath)
+}
+
 func GitDirExists(path string) (bool, error) {
 	info, err := os.Stat(filepath.Join(path, ".git"))
 	if err == nil {
@@ -219,42 +396,133 @@ func GitDirExists(path string) (bool, error) {
 	return false, err
 }
 
-func healConfigSchema(loadedCfg *MnemoConf, defaultCfg *MnemoConf) []error {
-	warnings := make([]error, 0)
-
-	loadedSchema := &loadedCfg.ConfigSchema
-	defaultSchema := defaultCfg.ConfigSchema
+// Validation rules shared by healConfigSchema and MnemoConf.Set, in the order
+// they are applied. Rules only run once the configuration is initialized.
+// Failing a repairable rule resets the field to its default when loading;
+// other failures are only reported, since they may be temporary (e.g. an
+// unmounted drive). MnemoConf.Set rejects both kinds.
+var configFieldRules = []struct {
+	key        string
+	repairable bool
+	check      func(schema *ConfigSchema) error
+}{
+	{"repo_path", true, func(schema *ConfigSchema) error {
+		if schema.RepoPath == "" {
+			return fmt.Errorf("Cannot be empty when initialized")
+		}
+		return nil
+	}},
+	{"repo_path", false, func(schema *ConfigSchema) error {
+		if schema.RepoPath == "" {
+			return nil
+		}
+		if _, err := os.Stat(schema.RepoPath); os.IsNotExist(err) {
+			return fmt.Errorf("Path does not exist on disk: %s", schema.RepoPath)
+		}
+		return nil
+	}},
+	{"db_path", true, func(schema *ConfigSchema) error {
+		if schema.DbPath == "" {
+			return fmt.Errorf("Cannot be empty when initialized")
+		}
+		return nil
+	}},
+	{"config_path", true, func(schema *ConfigSchema) error {
+		if schema.ConfigPath == "" {
+			return fmt.Errorf("File path mismatch: %s", schema.ConfigPath)
+		}
+		return nil
+	}},
+	{"repos", false, checkRepositories},
+	{"health.stale_days", true, func(schema *ConfigSchema) error {
+		if schema.Health.StaleDays < 0 {
+			return fmt.Errorf("Cannot be negative: %d", schema.Health.StaleDays)
+		}
+		return nil
+	}},
+	{"relocate.depth", true, func(schema *ConfigSchema) error {
+		if schema.Relocate.Depth < 1 {
+			return fmt.Errorf("Must be at least 1: %d", schema.Relocate.Depth)
+		}
+		return nil
+	}},
+	{"sync.jobs", true, func(schema *ConfigSchema) error {
+		if schema.Sync.Jobs < 1 {
+			return fmt.Errorf("Must be at least 1: %d", schema.Sync.Jobs)
+		}
+		return nil
+	}},
+	{"clean.keep", false, func(schema *ConfigSchema) error {
+		return checkPatterns(schema.Clean.Keep)
+	}},
+	{"deny.max_size", true, func(schema *ConfigSchema) error {
+		_, err := ParseSize(schema.Deny.MaxSize)
+		return err
+	}},
+	{"deny.paths", false, func(schema *ConfigSchema) error {
+		return checkPatterns(schema.Deny.Paths)
+	}},
+	{"deny.filesystems", false, func(schema *ConfigSchema) error {
+		return checkPatterns(schema.Deny.Filesystems)
+	}},
+	{"default_repo", true, func(schema *ConfigSchema) error {
+		if schema.DefaultRepo == "" || schema.DefaultRepo == DefaultRepoName {
+			return nil
+		}
+		if _, exists := schema.Repos[schema.DefaultRepo]; !exists {
+			return fmt.Errorf("Unknown repository '%s'", schema.DefaultRepo)
+		}
+		return nil
+	}},
+}
 
-	replaceField := func(field *string, defaultVal string, fieldName string, reason string) {
-		*field = defaultVal
-		warnings = append(warnings, fmt.Errorf("invalid or empty field '%s': %s Overridden with default: '%s'", fieldName, reason, defaultVal))
+func validateConfigField(key string, schema *ConfigSchema) error {
+	if !schema.IsInit {
+		return nil
 	}
-
-	if loadedSchema.AppVersion != defaultSchema.AppVersion {
-		replaceField(&loadedSchema.AppVersion, defaultSchema.AppVersion, "AppVersion", "")
+	for _, rule := range configFieldRules {
+		if rule.key != key {
+			continue
+		}
+		if err := rule.check(schema); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// Repairs invalid fields of loadedCfg in place. Returns the repairs that were
+// made and notices for problems that were deliberately left untouched.
+func healConfigSchema(loadedCfg *MnemoConf, defaultCfg *MnemoConf) ([]error, []error) {
+	repairs := make([]error, 0)
+	notices := make([]error, 0)
+
+	loadedSchema := &loadedCfg.ConfigSchema
+	defaultSchema := defaultCfg.ConfigSchema
 
 	if !loadedSchema.IsInit {
-		warnings = append(warnings, fmt.Errorf("found configuration file marked IsInit=false. Resetting RepoPath/DbPath."))
+		repairs = append(repairs, fmt.Errorf("found configuration file marked IsInit=false. Resetting RepoPath/DbPath."))
 
 		loadedSchema.RepoPath = defaultSchema.RepoPath
 		loadedSchema.DbPath = defaultSchema.DbPath
-		return warnings
-	}
-
-	if loadedSchema.RepoPath == "" {
-		replaceField(&loadedSchema.RepoPath, defaultSchema.RepoPath, "RepoPath", "Cannot be empty when initialized")
-	} else if _, err := os.Stat(loadedSchema.RepoPath); os.IsNotExist(err) {
-		replaceField(&loadedSchema.RepoPath, defaultSchema.RepoPath, "RepoPath", fmt.Sprintf("Path does not exist on disk: %s", loadedSchema.RepoPath))
+		return repairs, notices
 	}
 
-	if loadedSchema.DbPath == "" {
-		replaceField(&loadedSchema.DbPath, defaultSchema.DbPath, "DbPath", "Cannot be empty when initialized")
-	}
+	for _, rule := range configFieldRules {
+		err := rule.check(loadedSchema)
+		if err == nil {
+			continue
+		}
+		if !rule.repairable {
+			notices = append(notices, fmt.Errorf("field '%s': %v Left unchanged.", rule.key, err))
+			continue
+		}
 
-	if loadedSchema.ConfigPath == "" {
-		replaceField(&loadedSchema.ConfigPath, defaultSchema.ConfigPath, "ConfigPath", fmt.Sprintf("File path mismatch: %s", loadedSchema.ConfigPath))
+		field, _ := loadedCfg.lookupField(rule.key)
+		defaultField, _ := defaultCfg.lookupField(rule.key)
+		field.value.Set(defaultField.value)
+		repairs = append(repairs, fmt.Errorf("invalid or empty field '%s': %v Overridden with default: '%s'", rule.key, err, formatValue(defaultField.value)))
 	}
 
-	return warnings
+	return repairs, notices
 }
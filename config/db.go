@@ -5,25 +5,51 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"time"
 )
 
 type DirData struct {
-	TargetPath string `json:"target_path"`
-	Alias      string `json:"alias"`
+	TargetPath string `json:"target_path" yaml:"target_path"`
+	Alias      string `json:"alias" yaml:"alias"`
+	// Name of the target repository, empty for the one at repo_path
+	Repo string `json:"repo,omitempty" yaml:"repo,omitempty"`
+	// Sorted tags used to select groups of entries, e.g. tag:work
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Paused entries are skipped, until PausedUntil when it is set
+	Paused      bool       `json:"paused,omitempty" yaml:"paused,omitempty"`
+	PausedUntil *time.Time `json:"paused_until,omitempty" yaml:"paused_until,omitempty"`
 }
 type DataStore struct {
-	CurrentId   int64              `json:"current_id"`
-	TrackedDirs map[string]DirData `json:"tracked_dirs"`
+	SchemaVersion int                `json:"schema_version"`
+	CurrentId     int64              `json:"current_id"`
+	TrackedDirs   map[string]DirData `json:"tracked_dirs"`
 }
 
 func GetDataStore() *DataStore {
 	return &DataStore{
-		CurrentId:   0,
-		TrackedDirs: make(map[string]DirData),
+		SchemaVersion: CurrentDataStoreVersion,
+		CurrentId:     0,
+		TrackedDirs:   make(map[string]DirData),
 	}
 }
-func LoadDataStore() (*DataStore, error) {
+
+// LoadDataStore loads the database of the active profile. A database failing
+// schema validation is backed up and replaced with an empty one, and an old
+// one is migrated, unless healing is disabled for cfg as in LoadConfig.
+func LoadDataStore(cfg *MnemoConf) (*DataStore, error) {
+	return loadDataStore(cfg, false)
+}
+
+// LoadDataStoreReadOnly loads the database of the active profile without
+// repairing or saving anything.
+func LoadDataStoreReadOnly(cfg *MnemoConf) (*DataStore, error) {
+	return loadDataStore(cfg, true)
+}
+
+func loadDataStore(cfg *MnemoConf, readOnly bool) (*DataStore, error) {
+	heal := !readOnly && autoHealEnabled(cfg)
 	dbPath := ResolveDbPath()
 
 	defaultDS := GetDataStore()
@@ -37,14 +63,25 @@ func LoadDataStore() (*DataStore, error) {
 	}
 
 	tempDS := GetDataStore()
+	// Databases written before versioning have no schema_version
+	tempDS.SchemaVersion = 0
 
 	if err := json.Unmarshal(data, tempDS); err != nil {
 		return nil, fmt.Errorf("error unmarshalling JSON data from %s. File may be corrupt: %w", dbPath, err)
 	}
 
 	if err := validateDataStoreSchema(tempDS); err != nil {
+		if !heal {
+			return nil, fmt.Errorf("database at %s failed schema validation: %w", dbPath, err)
+		}
+
 		fmt.Fprintf(os.Stderr, "Warning: Database at %s failed schema validation: %v. Overwriting with default data.\n", dbPath, err)
 
+		if err := copyFile(dbPath, dbPath+".bak"); err != nil {
+			return nil, fmt.Errorf("failed to back up database before healing: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Previous database backed up to %s.bak\n", dbPath)
+
 		tempDS = defaultDS
 
 		if saveErr := tempDS.SaveData(dbPath); saveErr != nil {
@@ -54,6 +91,26 @@ func LoadDataStore() (*DataStore, error) {
 		return tempDS, nil
 	}
 
+	fromVersion, err := migrateDataStore(tempDS)
+	if err != nil {
+		return nil, err
+	}
+	if fromVersion != CurrentDataStoreVersion {
+		if !heal {
+			fmt.Fprintf(os.Stderr, "Warning: database uses schema version %d and will be migrated to %d once healing is allowed.\n", fromVersion, CurrentDataStoreVersion)
+			return tempDS, nil
+		}
+
+		backupPath := fmt.Sprintf("%s.v%d.bak", dbPath, fromVersion)
+		if err := copyFile(dbPath, backupPath); err != nil {
+			return nil, fmt.Errorf("failed to back up database before migration: %w", err)
+		}
+		if err := tempDS.SaveData(dbPath); err != nil {
+			return nil, fmt.Errorf("failed to save migrated database: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Migrated database from schema version %d to %d. Previous database backed up to %s\n", fromVersion, CurrentDataStoreVersion, backupPath)
+	}
+
 	return tempDS, nil
 }
 
@@ -64,6 +121,25 @@ func (ds *DataStore) AddDir(data DirData) string {
 	ds.TrackedDirs[newIDStr] = data
 	return newIDStr
 }
+
+// IDs returns the entry IDs in numeric order.
+func (ds *DataStore) IDs() []string {
+	return sortedIDs(ds.TrackedDirs)
+}
+
+func sortedIDs(dirs map[string]DirData) []string {
+	ids := make([]string, 0, len(dirs))
+	for id := range dirs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ids[i], 10, 64)
+		b, _ := strconv.ParseInt(ids[j], 10, 64)
+		return a < b
+	})
+	return ids
+}
+
 func (ds *DataStore) SaveData(targetPath string) error {
 	jsonData, err := json.MarshalIndent(ds, "", "  ")
 	if err != nil {
@@ -99,10 +175,21 @@ func validateDataStoreSchema(ds *DataStore) error {
 			return fmt.Errorf("entry with ID '%s' is missing a required alias", id)
 		}
 
-		if _, exists := seenTargetPaths[data.TargetPath]; exists {
+		// ~/x and /home/u/x are the same directory
+		targetPath, err := data.Path()
+		if err != nil {
+			targetPath = data.TargetPath
+		}
+		if _, exists := seenTargetPaths[targetPath]; exists {
 			return fmt.Errorf("duplicate target_path found: '%s'", data.TargetPath)
 		}
-		seenTargetPaths[data.TargetPath] = struct{}{}
+		seenTargetPaths[targetPath] = struct{}{}
+
+		for _, tag := range data.Tags {
+			if err := ValidateTag(tag); err != nil {
+				return fmt.Errorf("entry with ID '%s': %w", id, err)
+			}
+		}
 
 		if _, exists := seenAliases[data.Alias]; exists {
 			return fmt.Errorf("duplicate alias found: '%s'", data.Alias)
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DenyRules lists paths that must never be tracked.
+type DenyRules struct {
+	// Globs of refused paths. A ~, $HOME or $XDG_... prefix is expanded. A pattern
+	// without a separator matches a folder name anywhere, one ending in /** a whole subtree.
+	// Directories containing a match are refused as well.
+	Paths []string `yaml:"paths"`
+	// Largest directory that may be tracked, e.g. 500MB or 10GB. Empty or 0 disables the check.
+	MaxSize string `yaml:"max_size"`
+	// Filesystem types that are refused, as globs, e.g. nfs* or fuse.sshfs
+	Filesystems []string `yaml:"filesystems"`
+	// Refuse directories any user can write to
+	WorldWritable bool `yaml:"world_writable"`
+}
+
+func defaultDenyRules() DenyRules {
+	return DenyRules{
+		Paths: []string{
+			"/",
+			"~",
+			"~/.ssh/id_*",
+			"~/.gnupg/private-keys-v1.d",
+			"mnemosync",
+		},
+		MaxSize:       "10GB",
+		Filesystems:   []string{"nfs*", "cifs", "smb*", "fuse.sshfs", "fuse.rclone", "9p", "afs", "ceph", "glusterfs"},
+		WorldWritable: true,
+	}
+}
+
+// DenyError is a path refused by a deny rule.
+type DenyError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *DenyError) Error() string {
+	return fmt.Sprintf("%s (rule %s)", e.Reason, e.Rule)
+}
+
+// CheckDenyRules reports the first rule refusing the directory at path, which
+// must be absolute. Tracking a repository, or a directory inside a repository
+// or the configuration directory, is always refused.
+func (c *MnemoConf) CheckDenyRules(path string) error {
+	for _, repo := range c.Repositories() {
+		if repo.Path == "" {
+			continue
+		}
+		if rel, inside := relativeTo(repo.Path, path); inside && rel == "." {
+			return &DenyError{Rule: "repos", Reason: fmt.Sprintf("'%s' is repository '%s'", path, repo.Name)}
+		} else if inside {
+			return &DenyError{Rule: "repos", Reason: fmt.Sprintf("'%s' is inside repository '%s'", path, repo.Name)}
+		}
+		if _, contains := relativeTo(path, repo.Path); contains {
+			return &DenyError{Rule: "repos", Reason: fmt.Sprintf("'%s' contains repository '%s'", path, repo.Name)}
+		}
+	}
+	if _, inside := relativeTo(filepath.Dir(c.ConfigSchema.ConfigPath), path); inside {
+		return &DenyError{Rule: "config_path", Reason: fmt.Sprintf("'%s' is inside the configuration directory", path)}
+	}
+
+	rules := c.ConfigSchema.Deny
+
+	for _, pattern := range rules.Paths {
+		if err := matchDeniedPath(pattern, path); err != nil {
+			return err
+		}
+	}
+
+	if rules.WorldWritable {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0002 != 0 {
+			return &DenyError{Rule: "deny.world_writable", Reason: fmt.Sprintf("'%s' is world-writable", path)}
+		}
+	}
+
+	if len(rules.Filesystems) > 0 {
+		if fsType := filesystemType(path); fsType != "" {
+			for _, pattern := range rules.Filesystems {
+				if ok, _ := filepath.Match(pattern, fsType); ok {
+					return &DenyError{Rule: "deny.filesystems: " + pattern, Reason: fmt.Sprintf("'%s' is on a %s filesystem", path, fsType)}
+				}
+			}
+		}
+	}
+
+	limit, err := ParseSize(rules.MaxSize)
+	if err == nil && limit > 0 {
+		if size := DirectorySize(path, limit); size > limit {
+			return &DenyError{Rule: "deny.max_size: " + rules.MaxSize, Reason: fmt.Sprintf("'%s' is larger than %s", path, rules.MaxSize)}
+		}
+	}
+
+	return nil
+}
+
+func matchDeniedPath(pattern string, path string) error {
+	rule := "deny.paths: " + pattern
+	denied := func(reason string) error { return &DenyError{Rule: rule, Reason: reason} }
+
+	if !strings.ContainsRune(pattern, '/') && !strings.HasPrefix(pattern, "~") && !strings.HasPrefix(pattern, "$") {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return denied(fmt.Sprintf("'%s' is a refused folder name", path))
+		}
+		return nil
+	}
+
+	expanded, err := ExpandTargetPath(pattern)
+	if err != nil {
+		return nil
+	}
+
+	if prefix, ok := strings.CutSuffix(expanded, string(filepath.Separator)+"**"); ok {
+		if _, inside := relativeTo(prefix, path); inside {
+			return denied(fmt.Sprintf("'%s' is a refused path", path))
+		}
+		if _, contains := relativeTo(path, prefix); contains {
+			return denied(fmt.Sprintf("'%s' would include %s", path, prefix))
+		}
+		return nil
+	}
+
+	if ok, _ := filepath.Match(expanded, path); ok {
+		return denied(fmt.Sprintf("'%s' is a refused path", path))
+	}
+	matches, _ := filepath.Glob(expanded)
+	for _, match := range matches {
+		if rel, contains := relativeTo(path, match); contains && rel != "." {
+			return denied(fmt.Sprintf("'%s' would include %s", path, match))
+		}
+	}
+	return nil
+}
+
+// Reports the first malformed glob.
+func checkPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses sizes such as 512, 500MB or 1.5G into bytes. Units are
+// powers of 1024. An empty size is 0.
+func ParseSize(raw string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	if s == "" {
+		return 0, nil
+	}
+
+	factor := int64(1)
+	for _, unit := range sizeUnits {
+		if trimmed, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, factor = strings.TrimSpace(trimmed), unit.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a size such as 500MB or 10GB, got '%s'", raw)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// DirectorySize sums the size of the regular files under path, stopping once a
+// positive limit is exceeded. Unreadable entries are skipped.
+func DirectorySize(path string, limit int64) int64 {
+	var total int64
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		if limit > 0 && total > limit {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return total
+}
+
+// Returns the filesystem type of the mount holding path from /proc/self/mounts,
+// or an empty string where that is not available.
+func filesystemType(path string) string {
+	file, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	best, fsType := -1, ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		// Spaces and other special characters in mount points are octal escaped
+		mountPoint, err := strconv.Unquote(`"` + fields[1] + `"`)
+		if err != nil {
+			mountPoint = fields[1]
+		}
+		if _, inside := relativeTo(mountPoint, path); inside && len(mountPoint) >= best {
+			best, fsType = len(mountPoint), fields[2]
+		}
+	}
+	return fsType
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchDeniedPath(t *testing.T) {
+	home := t.TempDir()
+	for _, dir := range []string{".ssh/id_ed25519", "projects/app", "secret/keys"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		denied  bool
+	}{
+		{"root", "/", "/", true},
+		{"root does not match below it", "/", "/srv", false},
+		{"home", "~", home, true},
+		{"below home", "~", filepath.Join(home, "projects"), false},
+		{"folder name anywhere", "mnemosync", "/srv/mnemosync", true},
+		{"folder name glob", "*.tmp", "/srv/build.tmp", true},
+		{"folder name elsewhere in path", "mnemosync", "/srv/mnemosync/inner", false},
+		{"expanded glob", "~/.ssh/id_*", filepath.Join(home, ".ssh/id_ed25519"), true},
+		{"directory containing a match", "~/.ssh/id_*", filepath.Join(home, ".ssh"), true},
+		{"unrelated sibling of a match", "~/.ssh/id_*", filepath.Join(home, "projects"), false},
+		{"subtree root", "~/secret/**", filepath.Join(home, "secret"), true},
+		{"inside subtree", "~/secret/**", filepath.Join(home, "secret/keys"), true},
+		{"containing subtree", "~/secret/**", home, true},
+		{"outside subtree", "~/secret/**", filepath.Join(home, "projects"), false},
+		{"$HOME prefix", "$HOME/projects/**", filepath.Join(home, "projects/app"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPathEnv(t, home, nil)
+
+			err := matchDeniedPath(tt.pattern, tt.path)
+			if (err != nil) != tt.denied {
+				t.Errorf("matchDeniedPath(%q, %q) = %v, want denied %v", tt.pattern, tt.path, err, tt.denied)
+			}
+		})
+	}
+}
+
+func TestCheckDenyRules(t *testing.T) {
+	base := t.TempDir()
+	repo, other := filepath.Join(base, "repo"), filepath.Join(base, "notes")
+	open := filepath.Join(base, "open")
+	for _, dir := range []string{filepath.Join(repo, "alias"), other, open} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(open, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantRule string
+	}{
+		{"allowed", other, ""},
+		{"repository", repo, "repos"},
+		{"inside a repository", filepath.Join(repo, "alias"), "repos"},
+		{"containing a repository", base, "repos"},
+		{"configuration directory", filepath.Join(base, "config", "mmsync"), "config_path"},
+		{"world-writable", open, "deny.world_writable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPathEnv(t, filepath.Join(base, "home"), nil)
+
+			c := GetMnemoConf()
+			c.ConfigSchema.RepoPath = repo
+			c.ConfigSchema.ConfigPath = filepath.Join(base, "config", "mmsync", DefaultConfigFile)
+			c.ConfigSchema.Deny.Filesystems = nil
+
+			err := c.CheckDenyRules(tt.path)
+			if tt.wantRule == "" {
+				if err != nil {
+					t.Errorf("CheckDenyRules(%q) = %v, want nil", tt.path, err)
+				}
+				return
+			}
+			denyErr, ok := err.(*DenyError)
+			if !ok || denyErr.Rule != tt.wantRule {
+				t.Errorf("CheckDenyRules(%q) = %v, want rule %s", tt.path, err, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int64
+		wantErr bool
+	}{
+		{raw: "", want: 0},
+		{raw: "0", want: 0},
+		{raw: "512", want: 512},
+		{raw: "500MB", want: 500 << 20},
+		{raw: "10GB", want: 10 << 30},
+		{raw: "ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const envPrefix = "MMSYNC_"
+
+// EnvVarFor returns the environment variable overriding a configuration key,
+// e.g. MMSYNC_REPO_PATH for repo_path and MMSYNC_COMMIT_TEMPLATE for commit.template.
+// Entries of map keys such as repos are overridden with one variable each,
+// e.g. MMSYNC_REPOS_TEAM for the repository named team. The entry name is
+// matched regardless of case, so MMSYNC_REPOS_TEAM also overrides a
+// repository named Team; a new entry is named in lower case.
+func EnvVarFor(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Applies MMSYNC_* environment variables over the loaded values, ignoring empty
+// ones. The values read from the file are kept so that saveConfig never persists
+// an override.
+func (c *MnemoConf) applyEnvOverrides() error {
+	var overridden []string
+
+	for _, f := range c.fields() {
+		if f.noEnv {
+			continue
+		}
+
+		var value reflect.Value
+		var err error
+		if f.value.Kind() == reflect.Map {
+			var entries map[string]mapOverride
+			if value, entries, err = mapFromEnv(f); err == nil && value.IsValid() {
+				c.mapOverrides[f.key] = entries
+				f.value.Set(value)
+				c.sources[f.key] = SourceEnvironment
+				overridden = append(overridden, f.key)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		} else if raw := os.Getenv(EnvVarFor(f.key)); raw != "" {
+			value, err = parseValue(f.value, raw, f.isPath)
+			if err != nil {
+				err = fmt.Errorf("invalid value in $%s: %w", EnvVarFor(f.key), err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if !value.IsValid() {
+			continue
+		}
+
+		previous := reflect.New(f.value.Type()).Elem()
+		previous.Set(f.value)
+		c.fileValues[f.key] = previous
+
+		f.value.Set(value)
+		c.sources[f.key] = SourceEnvironment
+		overridden = append(overridden, f.key)
+	}
+
+	// Overrides cannot be healed, so anything a repair would touch is fatal
+	for _, rule := range configFieldRules {
+		if !c.ConfigSchema.IsInit || !containsKey(overridden, rule.key) {
+			continue
+		}
+		if err := rule.check(&c.ConfigSchema); err != nil {
+			if rule.repairable {
+				return fmt.Errorf("invalid value in $%s: %w", EnvVarFor(rule.key), err)
+			}
+			fmt.Fprintf(os.Stderr, "Config Warning: $%s: %v\n", EnvVarFor(rule.key), err)
+		}
+	}
+
+	return nil
+}
+
+// An environment override of one map entry, with the entry read from the
+// file, which is invalid when the file had none.
+type mapOverride struct {
+	value    reflect.Value
+	previous reflect.Value
+}
+
+// Builds a map from every MMSYNC_<KEY>_<NAME> variable, merged over the loaded
+// entries, and returns the overridden entries by name. A name matches an
+// existing entry regardless of case, and is lowercased otherwise. Returns an
+// invalid value when no variable is set.
+func mapFromEnv(f configField) (reflect.Value, map[string]mapOverride, error) {
+	prefix := EnvVarFor(f.key) + "_"
+	merged := reflect.MakeMap(f.value.Type())
+	overrides := make(map[string]mapOverride)
+
+	for _, k := range f.value.MapKeys() {
+		merged.SetMapIndex(k, f.value.MapIndex(k))
+	}
+
+	for _, kv := range os.Environ() {
+		name, raw, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) || raw == "" {
+			continue
+		}
+
+		elem := reflect.New(f.value.Type().Elem()).Elem()
+		parsed, err := parseValue(elem, raw, f.isPath)
+		if err != nil {
+			return reflect.Value{}, nil, fmt.Errorf("invalid value in $%s: %w", name, err)
+		}
+
+		entry := strings.ToLower(name[len(prefix):])
+		for _, k := range f.value.MapKeys() {
+			if strings.EqualFold(k.String(), entry) {
+				entry = k.String()
+				break
+			}
+		}
+		key := reflect.ValueOf(entry)
+		overrides[entry] = mapOverride{value: parsed, previous: f.value.MapIndex(key)}
+		merged.SetMapIndex(key, parsed)
+	}
+
+	if len(overrides) == 0 {
+		return reflect.Value{}, nil, nil
+	}
+	return merged, overrides, nil
+}
+
+// Returns a copy of the configuration with every environment override replaced
+// by the value read from the file. Map entries are restored one by one, so
+// entries added, changed or removed since loading, e.g. by repo add, are kept.
+func (c *MnemoConf) withoutEnvOverrides() *MnemoConf {
+	if len(c.fileValues) == 0 && len(c.mapOverrides) == 0 {
+		return c
+	}
+
+	stripped := &MnemoConf{ConfigSchema: c.ConfigSchema}
+	for _, f := range stripped.fields() {
+		if fileValue, ok := c.fileValues[f.key]; ok {
+			f.value.Set(fileValue)
+		}
+
+		overrides, ok := c.mapOverrides[f.key]
+		if !ok {
+			continue
+		}
+		restored := reflect.MakeMap(f.value.Type())
+		for _, k := range f.value.MapKeys() {
+			restored.SetMapIndex(k, f.value.MapIndex(k))
+		}
+		for entry, override := range overrides {
+			key := reflect.ValueOf(entry)
+			current := restored.MapIndex(key)
+			if !current.IsValid() || !reflect.DeepEqual(current.Interface(), override.value.Interface()) {
+				continue
+			}
+			// Deletes the entry when the file had none
+			restored.SetMapIndex(key, override.previous)
+		}
+		f.value.Set(restored)
+	}
+	return stripped
+}
+
+// IsEnvOverride reports whether the value of key currently comes from the environment.
+func (c *MnemoConf) IsEnvOverride(key string) bool {
+	_, ok := c.fileValues[key]
+	return ok || len(c.mapOverrides[key]) > 0
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
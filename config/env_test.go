@@ -0,0 +1,137 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvOverrideRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		repoPath string
+		repos    map[string]string
+		env      map[string]string
+		// Runs after the overrides are applied, e.g. a config set
+		change func(t *testing.T, c *MnemoConf)
+
+		wantRepoPath  string
+		wantRepos     map[string]string
+		savedRepoPath string
+		savedRepos    map[string]string
+	}{
+		{
+			name:          "scalar override is not saved",
+			repoPath:      "/srv/file",
+			env:           map[string]string{"MMSYNC_REPO_PATH": "/srv/env"},
+			wantRepoPath:  "/srv/env",
+			savedRepoPath: "/srv/file",
+		},
+		{
+			name:     "scalar set while overridden is saved",
+			repoPath: "/srv/file",
+			env:      map[string]string{"MMSYNC_REPO_PATH": "/srv/env"},
+			change: func(t *testing.T, c *MnemoConf) {
+				if err := c.Set("repo_path", "/srv/set"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRepoPath:  "/srv/set",
+			savedRepoPath: "/srv/set",
+		},
+		{
+			name:       "map entry matched regardless of case",
+			repos:      map[string]string{"Team": "/srv/team", "home": "/srv/home"},
+			env:        map[string]string{"MMSYNC_REPOS_TEAM": "/srv/env"},
+			wantRepos:  map[string]string{"Team": "/srv/env", "home": "/srv/home"},
+			savedRepos: map[string]string{"Team": "/srv/team", "home": "/srv/home"},
+		},
+		{
+			name:       "new map entry is lowercased and not saved",
+			repos:      map[string]string{"home": "/srv/home"},
+			env:        map[string]string{"MMSYNC_REPOS_WORK": "/srv/work"},
+			wantRepos:  map[string]string{"home": "/srv/home", "work": "/srv/work"},
+			savedRepos: map[string]string{"home": "/srv/home"},
+		},
+		{
+			name:  "entries added after loading are saved",
+			repos: map[string]string{"team": "/srv/team"},
+			env:   map[string]string{"MMSYNC_REPOS_TEAM": "/srv/env"},
+			change: func(t *testing.T, c *MnemoConf) {
+				c.ConfigSchema.Repos["new"] = "/srv/new"
+			},
+			wantRepos:  map[string]string{"team": "/srv/env", "new": "/srv/new"},
+			savedRepos: map[string]string{"team": "/srv/team", "new": "/srv/new"},
+		},
+		{
+			name:  "overridden entry removed after loading stays removed",
+			repos: map[string]string{"team": "/srv/team", "home": "/srv/home"},
+			env:   map[string]string{"MMSYNC_REPOS_TEAM": "/srv/env"},
+			change: func(t *testing.T, c *MnemoConf) {
+				delete(c.ConfigSchema.Repos, "team")
+			},
+			wantRepos:  map[string]string{"home": "/srv/home"},
+			savedRepos: map[string]string{"home": "/srv/home"},
+		},
+		{
+			name:  "overridden entry changed after loading keeps the change",
+			repos: map[string]string{"team": "/srv/team"},
+			env:   map[string]string{"MMSYNC_REPOS_TEAM": "/srv/env"},
+			change: func(t *testing.T, c *MnemoConf) {
+				c.ConfigSchema.Repos["team"] = "/srv/moved"
+			},
+			wantRepos:  map[string]string{"team": "/srv/moved"},
+			savedRepos: map[string]string{"team": "/srv/moved"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolatePaths(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			c := GetMnemoConf()
+			c.ConfigSchema.RepoPath = tt.repoPath
+			c.ConfigSchema.Repos = tt.repos
+			if err := c.applyEnvOverrides(); err != nil {
+				t.Fatalf("applyEnvOverrides() error = %v", err)
+			}
+			if tt.change != nil {
+				tt.change(t, c)
+			}
+
+			if c.ConfigSchema.RepoPath != tt.wantRepoPath {
+				t.Errorf("repo_path = %q, want %q", c.ConfigSchema.RepoPath, tt.wantRepoPath)
+			}
+			if !reflect.DeepEqual(c.ConfigSchema.Repos, tt.wantRepos) {
+				t.Errorf("repos = %v, want %v", c.ConfigSchema.Repos, tt.wantRepos)
+			}
+
+			saved := c.withoutEnvOverrides()
+			if saved.ConfigSchema.RepoPath != tt.savedRepoPath {
+				t.Errorf("saved repo_path = %q, want %q", saved.ConfigSchema.RepoPath, tt.savedRepoPath)
+			}
+			if !reflect.DeepEqual(saved.ConfigSchema.Repos, tt.savedRepos) {
+				t.Errorf("saved repos = %v, want %v", saved.ConfigSchema.Repos, tt.savedRepos)
+			}
+		})
+	}
+}
+
+func TestEnvVarFor(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"repo_path", "MMSYNC_REPO_PATH"},
+		{"health.stale_days", "MMSYNC_HEALTH_STALE_DAYS"},
+		{"deny.max_size", "MMSYNC_DENY_MAX_SIZE"},
+	}
+
+	for _, tt := range tests {
+		if got := EnvVarFor(tt.key); got != tt.want {
+			t.Errorf("EnvVarFor(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats supported by export and import.
+var ExportFormats = []string{"json", "yaml", "csv"}
+
+// Column order of the CSV format.
+var csvColumns = []string{"id", "alias", "target_path", "repo", "tags", "paused", "paused_until"}
+
+// ExportEntry is a tracked directory together with its ID, as written by export.
+type ExportEntry struct {
+	ID      string `json:"id" yaml:"id"`
+	DirData `yaml:",inline"`
+}
+
+// ExportEntries returns every tracked directory in ID order.
+func (ds *DataStore) ExportEntries() []ExportEntry {
+	entries := make([]ExportEntry, 0, len(ds.TrackedDirs))
+	for _, id := range ds.IDs() {
+		entries = append(entries, ExportEntry{ID: id, DirData: ds.TrackedDirs[id]})
+	}
+	return entries
+}
+
+// EncodeEntries writes entries in the given format.
+func EncodeEntries(w io.Writer, entries []ExportEntry, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal entries to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "yaml":
+		data, err := yaml.Marshal(entries)
+		if err != nil {
+			return fmt.Errorf("failed to marshal entries to YAML: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			pausedUntil := ""
+			if e.PausedUntil != nil {
+				pausedUntil = e.PausedUntil.Format(time.RFC3339)
+			}
+			record := []string{e.ID, e.Alias, e.TargetPath, e.Repo, strings.Join(e.Tags, ","), strconv.FormatBool(e.Paused), pausedUntil}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format '%s', expected one of: %s", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+// DecodeEntries reads entries written by EncodeEntries. CSV columns are
+// matched by the header row, so they may come in any order.
+func DecodeEntries(r io.Reader, format string) ([]ExportEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading entries: %w", err)
+	}
+
+	var entries []ExportEntry
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("error unmarshalling JSON entries: %w", err)
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("error unmarshalling YAML entries: %w", err)
+		}
+	case "csv":
+		if entries, err = decodeCSV(data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format '%s', expected one of: %s", format, strings.Join(ExportFormats, ", "))
+	}
+
+	for i, e := range entries {
+		if e.TargetPath == "" {
+			return nil, fmt.Errorf("entry %d is missing a required target_path", i+1)
+		}
+		if e.Alias == "" {
+			return nil, fmt.Errorf("entry %d is missing a required alias", i+1)
+		}
+		for _, tag := range e.Tags {
+			if err := ValidateTag(tag); err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i+1, err)
+			}
+		}
+	}
+	return entries, nil
+}
+
+func decodeCSV(data []byte) ([]ExportEntry, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV entries: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"alias", "target_path"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the '%s' column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	entries := make([]ExportEntry, 0, len(records)-1)
+	for _, record := range records[1:] {
+		var tags []string
+		for _, tag := range strings.Split(field(record, "tags"), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+
+		entry := ExportEntry{
+			ID: field(record, "id"),
+			DirData: DirData{
+				TargetPath: field(record, "target_path"),
+				Alias:      field(record, "alias"),
+				Repo:       field(record, "repo"),
+				Tags:       tags,
+			},
+		}
+
+		if raw := field(record, "paused"); raw != "" {
+			if entry.Paused, err = strconv.ParseBool(raw); err != nil {
+				return nil, fmt.Errorf("invalid paused value '%s' for '%s'", raw, entry.Alias)
+			}
+		}
+		if raw := field(record, "paused_until"); raw != "" {
+			until, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid paused_until value '%s' for '%s'", raw, entry.Alias)
+			}
+			entry.PausedUntil = &until
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
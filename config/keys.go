@@ -0,0 +1,277 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValueSource describes where an effective configuration value came from.
+type ValueSource string
+
+const (
+	SourceDefault     ValueSource = "default"
+	SourceFile        ValueSource = "file"
+	SourceEnvironment ValueSource = "environment"
+	SourceFlag        ValueSource = "flag"
+)
+
+// ConfigValue is a single effective configuration value as shown by `config list`.
+type ConfigValue struct {
+	Key    string
+	Value  string
+	Source ValueSource
+}
+
+type configField struct {
+	key      string
+	value    reflect.Value
+	isPath   bool
+	readOnly bool
+	noEnv    bool
+}
+
+// Walks the ConfigSchema struct and collects every scalar field under its
+// dotted yaml key path, e.g. "repo_path" or "commit.template".
+func collectFields(v reflect.Value, prefix string, out *[]configField) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		key := prefix + name
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			collectFields(fv, key+".", out)
+			continue
+		}
+
+		field := configField{key: key, value: fv}
+		for _, opt := range strings.Split(sf.Tag.Get("mmsync"), ",") {
+			switch opt {
+			case "path":
+				field.isPath = true
+			case "readonly":
+				field.readOnly = true
+			case "noenv":
+				field.noEnv = true
+			}
+		}
+		*out = append(*out, field)
+	}
+}
+
+func (c *MnemoConf) fields() []configField {
+	var out []configField
+	collectFields(reflect.ValueOf(&c.ConfigSchema).Elem(), "", &out)
+	return out
+}
+
+func (c *MnemoConf) lookupField(key string) (configField, error) {
+	for _, f := range c.fields() {
+		if f.key == key {
+			return f, nil
+		}
+	}
+	return configField{}, fmt.Errorf("unknown configuration key '%s'. Valid keys: %s", key, strings.Join(ConfigKeys(), ", "))
+}
+
+// ConfigKeys returns every configuration key path in schema order.
+func ConfigKeys() []string {
+	var keys []string
+	for _, f := range GetMnemoConf().fields() {
+		keys = append(keys, f.key)
+	}
+	return keys
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	case reflect.Map:
+		parts := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			parts = append(parts, fmt.Sprintf("%v=%s", k.Interface(), formatValue(v.MapIndex(k))))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func parseValue(target reflect.Value, raw string, isPath bool) (reflect.Value, error) {
+	parsed := reflect.New(target.Type()).Elem()
+
+	switch target.Kind() {
+	case reflect.String:
+		if isPath && raw != "" {
+			expanded, err := ExpandPath(raw)
+			if err != nil {
+				return parsed, err
+			}
+			raw = expanded
+		}
+		parsed.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return parsed, fmt.Errorf("expected a boolean (true/false), got '%s'", raw)
+		}
+		parsed.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, target.Type().Bits())
+		if err != nil {
+			return parsed, fmt.Errorf("expected an integer, got '%s'", raw)
+		}
+		parsed.SetInt(n)
+	case reflect.Slice:
+		if target.Type().Elem().Kind() != reflect.String {
+			return parsed, fmt.Errorf("unsupported list type %s", target.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		parsed.Set(reflect.ValueOf(items))
+	default:
+		return parsed, fmt.Errorf("unsupported value type %s", target.Type())
+	}
+
+	return parsed, nil
+}
+
+// Get returns the value stored under the dotted key path.
+func (c *MnemoConf) Get(key string) (string, error) {
+	field, err := c.lookupField(key)
+	if err != nil {
+		return "", err
+	}
+	return formatValue(field.value), nil
+}
+
+// Set parses raw into the type of the field under key, validates it with the
+// same rules used when healing a loaded configuration and assigns it.
+// The previous value is kept if parsing or validation fails.
+func (c *MnemoConf) Set(key string, raw string) error {
+	field, err := c.lookupField(key)
+	if err != nil {
+		return err
+	}
+	if field.readOnly {
+		return fmt.Errorf("configuration key '%s' is managed by mmsync and cannot be set", key)
+	}
+
+	parsed, err := parseValue(field.value, raw, field.isPath)
+	if err != nil {
+		return fmt.Errorf("invalid value for '%s': %w", key, err)
+	}
+
+	return c.assign(field, parsed)
+}
+
+// Unset restores the field under key to its default value.
+func (c *MnemoConf) Unset(key string) error {
+	field, err := c.lookupField(key)
+	if err != nil {
+		return err
+	}
+	if field.readOnly {
+		return fmt.Errorf("configuration key '%s' is managed by mmsync and cannot be unset", key)
+	}
+
+	defaultField, _ := GetMnemoConf().lookupField(key)
+	return c.assign(field, defaultField.value)
+}
+
+func (c *MnemoConf) assign(field configField, value reflect.Value) error {
+	previous := reflect.New(field.value.Type()).Elem()
+	previous.Set(field.value)
+
+	field.value.Set(value)
+	if err := validateConfigField(field.key, &c.ConfigSchema); err != nil {
+		field.value.Set(previous)
+		return fmt.Errorf("invalid value for '%s': %w", field.key, err)
+	}
+
+	// An explicitly set value is saved even while the environment overrides it
+	delete(c.fileValues, field.key)
+	delete(c.mapOverrides, field.key)
+	c.sources[field.key] = SourceFile
+	return nil
+}
+
+// Source reports where the effective value of key came from.
+func (c *MnemoConf) Source(key string) ValueSource {
+	if src, ok := c.sources[key]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// List returns every effective configuration value with its source, in schema order.
+func (c *MnemoConf) List() []ConfigValue {
+	var values []ConfigValue
+	for _, f := range c.fields() {
+		values = append(values, ConfigValue{
+			Key:    f.key,
+			Value:  formatValue(f.value),
+			Source: c.Source(f.key),
+		})
+	}
+	return values
+}
+
+// Collects the dotted key paths present under config_schema in a YAML document.
+func fileKeys(data []byte) map[string]bool {
+	keys := make(map[string]bool)
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return keys
+	}
+
+	var walk func(n *yaml.Node, prefix string)
+	walk = func(n *yaml.Node, prefix string) {
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := prefix + n.Content[i].Value
+			keys[key] = true
+			walk(n.Content[i+1], key+".")
+		}
+	}
+
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return keys
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == "config_schema" {
+			walk(doc.Content[i+1], "")
+		}
+	}
+	return keys
+}
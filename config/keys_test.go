@@ -0,0 +1,54 @@
+package config
+
+import "testing"
+
+func TestSetGetUnset(t *testing.T) {
+	tests := []struct {
+		key     string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{key: "sync.jobs", raw: "8", want: "8"},
+		{key: "sync.jobs", raw: "many", wantErr: true},
+		{key: "auto_heal", raw: "false", want: "false"},
+		{key: "health.stale_days", raw: "14", want: "14"},
+		{key: "relocate.roots", raw: "/srv/a,/srv/b", want: "/srv/a,/srv/b"},
+		{key: "db_path", raw: "/srv/db.json", wantErr: true},
+		{key: "schema_version", raw: "9", wantErr: true},
+		{key: "no.such.key", raw: "1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.raw, func(t *testing.T) {
+			isolatePaths(t)
+			c := GetMnemoConf()
+			before, _ := c.Get(tt.key)
+
+			err := c.Set(tt.key, tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q, %q) error = %v, want error %v", tt.key, tt.raw, err, tt.wantErr)
+			}
+			got, _ := c.Get(tt.key)
+			if tt.wantErr {
+				if got != before {
+					t.Errorf("failed Set changed %s from %q to %q", tt.key, before, got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+			if c.Source(tt.key) != SourceFile {
+				t.Errorf("Source(%q) = %v after Set", tt.key, c.Source(tt.key))
+			}
+
+			if err := c.Unset(tt.key); err != nil {
+				t.Fatalf("Unset(%q) error = %v", tt.key, err)
+			}
+			if got, _ := c.Get(tt.key); got != before {
+				t.Errorf("Get(%q) after Unset = %q, want %q", tt.key, got, before)
+			}
+		})
+	}
+}
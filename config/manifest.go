@@ -0,0 +1,154 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Files kept in a target repository for mmsync itself live under this directory.
+const (
+	MetadataDir     = ".mmsync"
+	ManifestFile    = "manifest.json"
+	ManifestVersion = 1
+)
+
+// Manifest is the copy of the tracked directories committed to a target
+// repository, so that tracking can be rebuilt from the repository alone.
+// Entries do not name a repository since the manifest lives in it.
+type Manifest struct {
+	ManifestVersion int                `json:"manifest_version"`
+	TrackedDirs     map[string]DirData `json:"tracked_dirs"`
+}
+
+// ManifestPath returns the manifest file of the repository at repoPath.
+func ManifestPath(repoPath string) string {
+	return filepath.Join(repoPath, MetadataDir, ManifestFile)
+}
+
+// AliasMetadataFile returns the sidecar keeping the file metadata git drops
+// for an alias folder, relative to its repository.
+func AliasMetadataFile(alias string) string {
+	return filepath.Join(MetadataDir, "metadata", alias+".json")
+}
+
+// AliasDir returns the folder mirroring a tracked directory inside its repository.
+func AliasDir(repoPath string, alias string) string {
+	return filepath.Join(repoPath, alias)
+}
+
+// WriteManifests writes the manifest of every configured repository that
+// exists on disk, each listing the entries routed to it.
+func (c *MnemoConf) WriteManifests(ds *DataStore) error {
+	manifests := make(map[string]*Manifest)
+	for _, repo := range c.Repositories() {
+		manifests[repo.Name] = &Manifest{ManifestVersion: ManifestVersion, TrackedDirs: make(map[string]DirData)}
+	}
+
+	for id, entry := range ds.TrackedDirs {
+		repo, err := c.RepositoryFor(entry)
+		if err != nil {
+			return fmt.Errorf("entry '%s' (ID: %s): %w", entry.Alias, id, err)
+		}
+		entry.Repo = ""
+		manifests[repo.Name].TrackedDirs[id] = entry
+	}
+
+	for _, repo := range c.Repositories() {
+		if exists, _ := GitDirExists(repo.Path); !exists {
+			continue
+		}
+
+		data, err := json.MarshalIndent(manifests[repo.Name], "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest: %w", err)
+		}
+
+		manifestPath := ManifestPath(repo.Path)
+		if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", manifestPath, err)
+		}
+		if err := writeFileAtomic(manifestPath, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write manifest %s: %w", manifestPath, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadManifest reads and validates the manifest of the repository at repoPath.
+func ReadManifest(repoPath string) (*Manifest, error) {
+	manifestPath := ManifestPath(repoPath)
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no manifest found at %s. Was the repository written by mmsync?", manifestPath)
+		}
+		return nil, fmt.Errorf("error reading manifest %s: %w", manifestPath, err)
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("error unmarshalling manifest %s. File may be corrupt: %w", manifestPath, err)
+	}
+	if manifest.ManifestVersion > ManifestVersion {
+		return nil, fmt.Errorf("manifest version %d is newer than supported version %d. Upgrade mmsync", manifest.ManifestVersion, ManifestVersion)
+	}
+	if err := validateDataStoreSchema(&DataStore{TrackedDirs: manifest.TrackedDirs}); err != nil {
+		return nil, fmt.Errorf("manifest %s is invalid: %w", manifestPath, err)
+	}
+
+	return manifest, nil
+}
+
+// IDs returns the manifest entry IDs in numeric order.
+func (m *Manifest) IDs() []string {
+	return sortedIDs(m.TrackedDirs)
+}
+
+// RestoreDir copies a directory tree from the repository back to its target
+// path. Existing files are left alone.
+func RestoreDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Lstat(target); err == nil {
+				return nil
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			if _, err := os.Lstat(target); err == nil {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if err := copyFile(path, target); err != nil {
+				return err
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentSchemaVersion is the configuration schema version written by this binary.
+// Bump it together with a new entry in configMigrations whenever the on-disk
+// layout of MnemoConf changes.
+const CurrentSchemaVersion = 1
+
+type configMigration struct {
+	from        int
+	description string
+	apply       func(schema map[string]any) error
+}
+
+// Ordered chain of migrations. Each entry upgrades a raw config_schema
+// mapping from version `from` to `from+1`.
+var configMigrations = []configMigration{
+	{
+		from:        0,
+		description: "record schema_version separately from app_version",
+		apply: func(schema map[string]any) error {
+			return nil
+		},
+	},
+}
+
+func schemaVersionOf(schema map[string]any) (int, error) {
+	raw, ok := schema["schema_version"]
+	if !ok {
+		return 0, nil
+	}
+	version, ok := raw.(int)
+	if !ok || version < 0 {
+		return 0, fmt.Errorf("schema_version must be a non-negative integer, found: %v", raw)
+	}
+	return version, nil
+}
+
+// Upgrades raw configuration data to CurrentSchemaVersion in memory.
+// Returns the migrated data and the schema version it was migrated from.
+func migrateConfigSchema(data []byte) ([]byte, int, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, fmt.Errorf("error unmarshalling YAML data. File may be invalid: %w", err)
+	}
+
+	schema, ok := doc["config_schema"].(map[string]any)
+	if !ok {
+		return data, CurrentSchemaVersion, nil
+	}
+
+	fromVersion, err := schemaVersionOf(schema)
+	if err != nil {
+		return nil, 0, err
+	}
+	if fromVersion > CurrentSchemaVersion {
+		return nil, 0, fmt.Errorf("configuration schema version %d is newer than supported version %d. Upgrade mmsync", fromVersion, CurrentSchemaVersion)
+	}
+	if fromVersion == CurrentSchemaVersion {
+		return data, fromVersion, nil
+	}
+
+	version := fromVersion
+	for _, m := range configMigrations {
+		if m.from != version {
+			continue
+		}
+		if err := m.apply(schema); err != nil {
+			return nil, 0, fmt.Errorf("migration from schema version %d (%s) failed: %w", m.from, m.description, err)
+		}
+		version = m.from + 1
+		schema["schema_version"] = version
+	}
+
+	if version != CurrentSchemaVersion {
+		return nil, 0, fmt.Errorf("no migration path from schema version %d to %d", version, CurrentSchemaVersion)
+	}
+
+	migrated, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal migrated configuration: %w", err)
+	}
+
+	return migrated, fromVersion, nil
+}
+
+// CurrentDataStoreVersion is the database schema version written by this binary.
+const CurrentDataStoreVersion = 1
+
+type dataStoreMigration struct {
+	from        int
+	description string
+	apply       func(ds *DataStore) error
+}
+
+// Ordered chain of database migrations, each upgrading from version `from` to `from+1`.
+var dataStoreMigrations = []dataStoreMigration{
+	{
+		from:        0,
+		description: "store target paths relative to the home directory",
+		apply: func(ds *DataStore) error {
+			for id, entry := range ds.TrackedDirs {
+				entry.TargetPath = PortablePath(entry.TargetPath)
+				ds.TrackedDirs[id] = entry
+			}
+			return nil
+		},
+	},
+}
+
+// Upgrades a database to CurrentDataStoreVersion in memory. Returns the schema
+// version it was migrated from.
+func migrateDataStore(ds *DataStore) (int, error) {
+	fromVersion := ds.SchemaVersion
+	if fromVersion > CurrentDataStoreVersion {
+		return 0, fmt.Errorf("database schema version %d is newer than supported version %d. Upgrade mmsync", fromVersion, CurrentDataStoreVersion)
+	}
+
+	for _, m := range dataStoreMigrations {
+		if m.from != ds.SchemaVersion {
+			continue
+		}
+		if err := m.apply(ds); err != nil {
+			return 0, fmt.Errorf("database migration from schema version %d (%s) failed: %w", m.from, m.description, err)
+		}
+		ds.SchemaVersion = m.from + 1
+	}
+
+	if ds.SchemaVersion != CurrentDataStoreVersion {
+		return 0, fmt.Errorf("no migration path from database schema version %d to %d", ds.SchemaVersion, CurrentDataStoreVersion)
+	}
+	return fromVersion, nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+// Top-level names of a repository that are never orphans, besides the aliases
+// routed to it and the patterns in clean.keep.
+var repoMetadataNames = []string{".git", MetadataDir}
+
+// Orphan is a top-level entry of a repository that mirrors no tracked directory.
+type Orphan struct {
+	Name  string
+	Path  string
+	IsDir bool
+}
+
+// Orphans returns the top-level files and folders of repo that are neither
+// the copy of a tracked directory routed to it, mmsync or git metadata, nor
+// matched by clean.keep. They are left behind by removed or renamed aliases,
+// or were put there by hand.
+func (c *MnemoConf) Orphans(repo Repository, ds *DataStore) ([]Orphan, error) {
+	known := make(map[string]bool)
+	for _, name := range repoMetadataNames {
+		known[name] = true
+	}
+	for _, entry := range ds.TrackedDirs {
+		if entryRepo, err := c.RepositoryFor(entry); err == nil && entryRepo.Name == repo.Name {
+			known[entry.Alias] = true
+		}
+	}
+
+	entries, err := os.ReadDir(repo.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository %s: %w", repo.Path, err)
+	}
+
+	var orphans []Orphan
+	for _, e := range entries {
+		if known[e.Name()] || c.keptInRepo(e.Name()) {
+			continue
+		}
+		orphans = append(orphans, Orphan{Name: e.Name(), Path: filepath.Join(repo.Path, e.Name()), IsDir: e.IsDir()})
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Name < orphans[j].Name })
+	return orphans, nil
+}
+
+func (c *MnemoConf) keptInRepo(name string) bool {
+	for _, pattern := range c.ConfigSchema.Clean.Keep {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// InGit reports whether git tracks the orphan or anything inside it.
+func (o Orphan) InGit() bool {
+	cmd := exec.Command("git", "-C", filepath.Dir(o.Path), "ls-files", "--error-unmatch", "--", o.Name)
+	return cmd.Run() == nil
+}
+
+// Remove deletes the orphan, with git rm when git tracks it so that the
+// removal is staged for the next commit.
+func (o Orphan) Remove() error {
+	if o.InGit() {
+		output, err := exec.Command("git", "-C", filepath.Dir(o.Path), "rm", "-r", "-q", "--", o.Name).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("git rm %s failed: %w: %s", o.Name, err, output)
+		}
+	}
+	if err := os.RemoveAll(o.Path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", o.Path, err)
+	}
+	return nil
+}
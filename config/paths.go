@@ -0,0 +1,314 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultStateDir = ".local/state/mmsync"
+	xdgAppDir       = "mmsync"
+)
+
+// Configuration file passed with --config. Takes precedence over $MMSYNC_CONF.
+var configFileOverride string
+
+// SetConfigFile selects the configuration file of the default profile, as given with --config.
+// Relative paths are resolved against the working directory.
+func SetConfigFile(path string) error {
+	if path == "" {
+		configFileOverride = ""
+		return nil
+	}
+	resolved, err := ExpandPath(path)
+	if err != nil {
+		return err
+	}
+	configFileOverride = resolved
+	return nil
+}
+
+// PathCandidate is one step in the precedence order used to resolve a file.
+type PathCandidate struct {
+	Source string
+	Path   string
+	Note   string
+	Used   bool
+}
+
+// Points a path naming a directory (existing, or written with a trailing
+// separator) at the configuration file inside it. Any other path is taken
+// as the configuration file itself.
+func configFileIn(path string) (string, string) {
+	if strings.HasSuffix(path, string(os.PathSeparator)) {
+		return filepath.Join(path, DefaultConfigFile), "directory, using " + DefaultConfigFile + " inside it"
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, DefaultConfigFile), "directory, using " + DefaultConfigFile + " inside it"
+	}
+	return path, ""
+}
+
+// ExplainConfigPath returns, in order of precedence, every place the configuration
+// file of the default profile may come from. The first candidate with a path is used.
+func ExplainConfigPath() []PathCandidate {
+	homeDir, homeErr := os.UserHomeDir()
+
+	var candidates []PathCandidate
+
+	flagCandidate := PathCandidate{Source: "--config flag"}
+	if configFileOverride != "" {
+		flagCandidate.Path, flagCandidate.Note = configFileIn(configFileOverride)
+	}
+	candidates = append(candidates, flagCandidate)
+
+	envCandidate := PathCandidate{Source: "$MMSYNC_CONF"}
+	if envPath := os.Getenv("MMSYNC_CONF"); envPath != "" {
+		if strings.HasPrefix(envPath, "~") {
+			envPath, _ = ExpandPath(envPath)
+		}
+		if !filepath.IsAbs(envPath) && homeErr == nil {
+			envPath = filepath.Join(homeDir, envPath)
+		}
+		envCandidate.Path, envCandidate.Note = configFileIn(envPath)
+	}
+	candidates = append(candidates, envCandidate)
+
+	xdgCandidate := PathCandidate{Source: "$XDG_CONFIG_HOME"}
+	if xdgHome := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdgHome) {
+		xdgCandidate.Path = filepath.Join(xdgHome, xdgAppDir, DefaultConfigFile)
+	}
+	candidates = append(candidates, xdgCandidate)
+
+	defaultCandidate := PathCandidate{Source: "default", Path: filepath.Join(DefaultConfigDir, DefaultConfigFile)}
+	if homeErr == nil {
+		defaultCandidate.Path = filepath.Join(homeDir, DefaultConfigDir, DefaultConfigFile)
+	}
+	candidates = append(candidates, defaultCandidate)
+
+	for i := range candidates {
+		if candidates[i].Path != "" {
+			candidates[i].Used = true
+			break
+		}
+	}
+	return candidates
+}
+
+// Returns the candidate used to resolve the configuration file of the default profile.
+func configPathSource() PathCandidate {
+	for _, c := range ExplainConfigPath() {
+		if c.Used {
+			return c
+		}
+	}
+	return PathCandidate{}
+}
+
+// Resolves the configuration file of the default profile.
+func resolveBaseConfigPath() string {
+	return configPathSource().Path
+}
+
+// Reports whether the configuration file was chosen explicitly with --config or
+// $MMSYNC_CONF. The database is then kept next to it, as before XDG support.
+func explicitConfigFile() bool {
+	source := configPathSource().Source
+	return source == "--config flag" || source == "$MMSYNC_CONF"
+}
+
+// StateDir returns the base directory holding the databases of all profiles.
+func StateDir() string {
+	if xdgState := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(xdgState) {
+		return filepath.Join(xdgState, xdgAppDir)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return DefaultStateDir
+	}
+	return filepath.Join(homeDir, DefaultStateDir)
+}
+
+func profileSubdir(name string) string {
+	if name == DefaultProfile {
+		return ""
+	}
+	return filepath.Join(DefaultProfilesDir, name)
+}
+
+// ProfileConfigPath returns the configuration file of a profile.
+func ProfileConfigPath(name string) string {
+	if name == DefaultProfile {
+		return resolveBaseConfigPath()
+	}
+	return filepath.Join(ConfigDir(), profileSubdir(name), DefaultConfigFile)
+}
+
+// ExplainDbPath returns, in order of precedence, every place the database of a
+// profile may come from, marking the one in use.
+func ExplainDbPath(name string) []PathCandidate {
+	legacyPath := filepath.Join(filepath.Dir(ProfileConfigPath(name)), DefaultDbFile)
+
+	if envPath := os.Getenv(EnvVarFor("db_path")); envPath != "" {
+		if resolved, err := ExpandPath(envPath); err == nil {
+			return []PathCandidate{{Source: "$" + EnvVarFor("db_path"), Path: resolved, Used: true}}
+		}
+	}
+
+	if explicitConfigFile() {
+		return []PathCandidate{{
+			Source: "next to configuration file",
+			Path:   legacyPath,
+			Note:   "configuration file set with --config or $MMSYNC_CONF",
+			Used:   true,
+		}}
+	}
+
+	stateCandidate := PathCandidate{Source: "state directory", Path: filepath.Join(StateDir(), profileSubdir(name), DefaultDbFile)}
+	if filepath.IsAbs(os.Getenv("XDG_STATE_HOME")) {
+		stateCandidate.Source = "$XDG_STATE_HOME"
+	}
+	legacyCandidate := PathCandidate{Source: "legacy, next to configuration file", Path: legacyPath, Note: "only used while the state directory has no database. Run mmsync config migrate to move it"}
+
+	_, stateErr := os.Stat(stateCandidate.Path)
+	_, legacyErr := os.Stat(legacyCandidate.Path)
+	if os.IsNotExist(stateErr) && legacyErr == nil {
+		legacyCandidate.Used = true
+	} else {
+		stateCandidate.Used = true
+	}
+
+	return []PathCandidate{stateCandidate, legacyCandidate}
+}
+
+// ProfileDbPath returns the database file of a profile.
+func ProfileDbPath(name string) string {
+	for _, c := range ExplainDbPath(name) {
+		if c.Used {
+			return c.Path
+		}
+	}
+	return ""
+}
+
+// Returns where the database of a profile belongs, ignoring the legacy fallback.
+func preferredDbPath(name string) string {
+	return ExplainDbPath(name)[0].Path
+}
+
+// MigrateConfigData copies the configuration and databases of every profile found in
+// fromDir, laid out as before XDG support, to their currently resolved locations.
+// Files already in place are left alone. With removeOld the copied source files are
+// deleted afterwards. Returns a description of each step taken.
+func MigrateConfigData(fromDir string, removeOld bool) ([]string, error) {
+	var steps []string
+
+	if _, err := os.Stat(fromDir); err != nil {
+		return nil, fmt.Errorf("cannot read source directory %s: %w", fromDir, err)
+	}
+
+	profiles := []string{DefaultProfile}
+	entries, _ := os.ReadDir(filepath.Join(fromDir, DefaultProfilesDir))
+	for _, entry := range entries {
+		if entry.IsDir() && profileNamePattern.MatchString(entry.Name()) {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+
+	type move struct {
+		src, dst string
+		done     bool
+	}
+	var plan []move
+	rewrites := make(map[string]string)
+
+	for _, name := range profiles {
+		srcDir := filepath.Join(fromDir, profileSubdir(name))
+		srcConfig := filepath.Join(srcDir, DefaultConfigFile)
+		srcDb := filepath.Join(srcDir, DefaultDbFile)
+		dstConfig := ProfileConfigPath(name)
+		dstDb := preferredDbPath(name)
+
+		if _, err := os.Stat(srcConfig); err != nil {
+			continue
+		}
+
+		for _, m := range []move{{src: srcConfig, dst: dstConfig}, {src: srcDb, dst: dstDb}} {
+			if _, err := os.Stat(m.src); err != nil || m.src == m.dst {
+				continue
+			}
+			if _, err := os.Stat(m.dst); err == nil {
+				// A previous run without --remove-old leaves the database copied
+				// as is and the configuration copied with its paths rewritten
+				done := sameContent(m.src, m.dst) || m.src == srcConfig && migratedConfig(srcConfig, dstConfig, dstDb)
+				if !done {
+					return nil, fmt.Errorf("cannot migrate: %s already exists", m.dst)
+				}
+				m.done = true
+			}
+			plan = append(plan, m)
+		}
+		if !migratedConfig(srcConfig, dstConfig, dstDb) {
+			rewrites[dstConfig] = dstDb
+		}
+	}
+
+	for _, m := range plan {
+		if m.done {
+			continue
+		}
+		if err := copyFile(m.src, m.dst); err != nil {
+			return steps, err
+		}
+		steps = append(steps, fmt.Sprintf("copied %s to %s", m.src, m.dst))
+	}
+
+	for configPath, dbPath := range rewrites {
+		if err := rewriteConfigPaths(configPath, dbPath); err != nil {
+			return steps, err
+		}
+	}
+
+	if !removeOld {
+		return steps, nil
+	}
+
+	for _, m := range plan {
+		if err := os.Remove(m.src); err != nil {
+			return steps, fmt.Errorf("failed to remove %s: %w", m.src, err)
+		}
+		steps = append(steps, fmt.Sprintf("removed %s", m.src))
+		// Clean up directories left empty; failures mean they still hold other files
+		os.Remove(filepath.Dir(m.src))
+	}
+
+	return steps, nil
+}
+
+// Reports whether dstConfig holds srcConfig with its paths rewritten to
+// dstConfig and dstDb, as a previous migration leaves it.
+func migratedConfig(srcConfig string, dstConfig string, dstDb string) bool {
+	data, err := os.ReadFile(srcConfig)
+	if err != nil {
+		return false
+	}
+	cfg, err := configWithPaths(data, dstConfig, dstDb)
+	if err != nil {
+		return false
+	}
+	want, err := marshalConfig(cfg)
+	if err != nil {
+		return false
+	}
+	got, err := os.ReadFile(dstConfig)
+	return err == nil && bytes.Equal(got, want)
+}
+
+func sameContent(a, b string) bool {
+	dataA, errA := os.ReadFile(a)
+	dataB, errB := os.ReadFile(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
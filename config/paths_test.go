@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Points every configuration and state location into a temporary directory
+// and returns it.
+func isolatePaths(t *testing.T) string {
+	t.Helper()
+	base := t.TempDir()
+	t.Setenv("HOME", filepath.Join(base, "home"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(base, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(base, "state"))
+	t.Setenv("MMSYNC_CONF", "")
+	t.Setenv(EnvVarFor("db_path"), "")
+	if err := SetConfigFile(""); err != nil {
+		t.Fatal(err)
+	}
+	return base
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateConfigDataRerun(t *testing.T) {
+	base := isolatePaths(t)
+	from := filepath.Join(base, "old")
+	writeTestFile(t, filepath.Join(from, DefaultConfigFile), "config_schema:\n  repo_path: /srv/notes\n  is_init: true\n")
+	writeTestFile(t, filepath.Join(from, DefaultDbFile), `{"schema_version": 1}`)
+	writeTestFile(t, filepath.Join(from, DefaultProfilesDir, "work", DefaultConfigFile), "config_schema:\n  repo_path: /srv/work\n")
+
+	tests := []struct {
+		name      string
+		removeOld bool
+		wantSteps int
+	}{
+		{"first run copies", false, 3},
+		{"second run finds the copies", false, 0},
+		{"third run removes the sources", true, 3},
+	}
+	for _, tt := range tests {
+		steps, err := MigrateConfigData(from, tt.removeOld)
+		if err != nil {
+			t.Fatalf("%s: MigrateConfigData() error = %v", tt.name, err)
+		}
+		if len(steps) != tt.wantSteps {
+			t.Errorf("%s: steps = %q, want %d", tt.name, steps, tt.wantSteps)
+		}
+	}
+
+	data, err := os.ReadFile(ProfileConfigPath(DefaultProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := GetMnemoConf()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ConfigSchema.RepoPath != "/srv/notes" || cfg.ConfigSchema.DbPath != preferredDbPath(DefaultProfile) {
+		t.Errorf("migrated config has repo_path %q and db_path %q", cfg.ConfigSchema.RepoPath, cfg.ConfigSchema.DbPath)
+	}
+	if _, err := os.Stat(filepath.Join(from, DefaultConfigFile)); !os.IsNotExist(err) {
+		t.Errorf("source config still there after --remove-old: %v", err)
+	}
+}
+
+func TestMigrateConfigDataConflict(t *testing.T) {
+	base := isolatePaths(t)
+	from := filepath.Join(base, "old")
+	writeTestFile(t, filepath.Join(from, DefaultConfigFile), "config_schema:\n  repo_path: /srv/notes\n")
+	writeTestFile(t, ProfileConfigPath(DefaultProfile), "config_schema:\n  repo_path: /srv/other\n")
+
+	if _, err := MigrateConfigData(from, false); err == nil {
+		t.Error("MigrateConfigData() overwrote a different configuration")
+	}
+}
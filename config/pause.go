@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IsPaused reports whether the entry is paused at now. A pause with an end
+// date expires on its own.
+func (d DirData) IsPaused(now time.Time) bool {
+	if !d.Paused {
+		return false
+	}
+	return d.PausedUntil == nil || now.Before(*d.PausedUntil)
+}
+
+// PauseStatus describes the pause state at now for list and health output.
+func (d DirData) PauseStatus(now time.Time) string {
+	switch {
+	case !d.Paused:
+		return "active"
+	case d.PausedUntil == nil:
+		return "paused"
+	case d.IsPaused(now):
+		return "paused until " + d.PausedUntil.Local().Format(time.DateTime)
+	default:
+		return "active (pause expired " + d.PausedUntil.Local().Format(time.DateTime) + ")"
+	}
+}
+
+// ParsePauseEnd parses the end of a pause: a date (2006-01-02, midnight local
+// time), an RFC 3339 timestamp, or a duration from now such as 36h or 7d.
+func ParsePauseEnd(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+
+	if t, err := time.ParseInLocation(time.DateOnly, raw, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, n), nil
+		}
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("expected a date (2006-01-02), an RFC 3339 time or a duration such as 36h or 7d, got '%s'", raw)
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Variables allowed in stored paths besides HOME, with the directory used when
+// they are unset, relative to the home directory.
+var pathVariables = []struct {
+	name     string
+	fallback string
+}{
+	{"XDG_CONFIG_HOME", ".config"},
+	{"XDG_DATA_HOME", ".local/share"},
+	{"XDG_STATE_HOME", ".local/state"},
+	{"XDG_CACHE_HOME", ".cache"},
+}
+
+// PortablePath rewrites an absolute path so that it survives a different home
+// directory: paths under an explicitly set XDG base directory become
+// ${XDG_...}/..., other paths under the home directory become ~/....
+// Anything else is returned unchanged.
+func PortablePath(path string) string {
+	for _, v := range pathVariables {
+		if dir := os.Getenv(v.name); dir != "" {
+			if rel, ok := relativeTo(dir, path); ok {
+				return joinPortable("${"+v.name+"}", rel)
+			}
+		}
+	}
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		if rel, ok := relativeTo(homeDir, path); ok {
+			return joinPortable("~", rel)
+		}
+	}
+	return path
+}
+
+// ExpandTargetPath turns a stored path back into an absolute path on this
+// machine. Only a leading ~, $HOME or ${XDG_...} component is expanded;
+// any other $ is part of a directory name.
+func ExpandTargetPath(stored string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	first, rest, _ := strings.Cut(filepath.ToSlash(stored), "/")
+	expanded := stored
+	switch {
+	case first == "~":
+		expanded = filepath.Join(homeDir, rest)
+	case strings.HasPrefix(first, "$"):
+		name := strings.TrimPrefix(first, "$")
+		if strings.HasPrefix(name, "{") && strings.HasSuffix(name, "}") {
+			name = name[1 : len(name)-1]
+		}
+		if dir, ok := pathVariable(name, homeDir); ok {
+			expanded = filepath.Join(dir, rest)
+		}
+	}
+
+	if !filepath.IsAbs(expanded) {
+		return "", fmt.Errorf("path '%s' does not expand to an absolute path", stored)
+	}
+	return filepath.Clean(expanded), nil
+}
+
+// Returns the directory of HOME or one of the pathVariables, falling back
+// to its default when it is unset. Reports false for any other name.
+func pathVariable(name string, homeDir string) (string, bool) {
+	if name == "HOME" {
+		if value := os.Getenv(name); value != "" {
+			return value, true
+		}
+		return homeDir, true
+	}
+	for _, v := range pathVariables {
+		if v.name == name {
+			if value := os.Getenv(name); value != "" {
+				return value, true
+			}
+			return filepath.Join(homeDir, v.fallback), true
+		}
+	}
+	return "", false
+}
+
+// Path returns the absolute target path of a tracked directory on this machine.
+func (d DirData) Path() (string, error) {
+	return ExpandTargetPath(d.TargetPath)
+}
+
+func relativeTo(base string, path string) (string, bool) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+func joinPortable(prefix string, rel string) string {
+	if rel == "." {
+		return prefix
+	}
+	return prefix + "/" + filepath.ToSlash(rel)
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// Sets HOME and the XDG base directories for a test, "" unsetting them.
+func setPathEnv(t *testing.T, home string, xdg map[string]string) {
+	t.Helper()
+	t.Setenv("HOME", home)
+	for _, v := range pathVariables {
+		t.Setenv(v.name, xdg[v.name])
+	}
+}
+
+func TestExpandTargetPath(t *testing.T) {
+	tests := []struct {
+		stored  string
+		xdg     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{stored: "~", want: "/home/u"},
+		{stored: "~/notes", want: "/home/u/notes"},
+		{stored: "$HOME/notes", want: "/home/u/notes"},
+		{stored: "${HOME}/notes", want: "/home/u/notes"},
+		{stored: "${XDG_CONFIG_HOME}/app", want: "/home/u/.config/app"},
+		{stored: "${XDG_CONFIG_HOME}/app", xdg: map[string]string{"XDG_CONFIG_HOME": "/cfg"}, want: "/cfg/app"},
+		{stored: "$XDG_STATE_HOME", want: "/home/u/.local/state"},
+		{stored: "/srv/price$5", want: "/srv/price$5"},
+		{stored: "/srv/$HOME/x", want: "/srv/$HOME/x"},
+		{stored: "/srv/notes/../other", want: "/srv/other"},
+		{stored: "~user/notes", wantErr: true},
+		{stored: "$UNKNOWN/notes", wantErr: true},
+		{stored: "notes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stored, func(t *testing.T) {
+			setPathEnv(t, "/home/u", tt.xdg)
+			t.Setenv("UNKNOWN", "/unknown")
+
+			got, err := ExpandTargetPath(tt.stored)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandTargetPath(%q) error = %v, want error %v", tt.stored, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ExpandTargetPath(%q) = %q, want %q", tt.stored, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortablePath(t *testing.T) {
+	tests := []struct {
+		path string
+		xdg  map[string]string
+		want string
+	}{
+		{path: "/home/u", want: "~"},
+		{path: "/home/u/notes", want: "~/notes"},
+		{path: "/home/u/.config/app", want: "~/.config/app"},
+		{path: "/cfg/app", xdg: map[string]string{"XDG_CONFIG_HOME": "/cfg"}, want: "${XDG_CONFIG_HOME}/app"},
+		{path: "/home/user2/notes", want: "/home/user2/notes"},
+		{path: "/srv/notes", want: "/srv/notes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			setPathEnv(t, "/home/u", tt.xdg)
+
+			got := PortablePath(tt.path)
+			if got != tt.want {
+				t.Errorf("PortablePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+			if back, err := ExpandTargetPath(got); err != nil || back != tt.path {
+				t.Errorf("ExpandTargetPath(%q) = %q, %v, want %q", got, back, err, tt.path)
+			}
+		})
+	}
+}
+
+func TestValidateDataStoreSchemaDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		dirs    map[string]DirData
+		wantErr string
+	}{
+		{"distinct", map[string]DirData{
+			"1": {TargetPath: "~/notes", Alias: "notes"},
+			"2": {TargetPath: "/srv/notes", Alias: "srv"},
+		}, ""},
+		{"same stored path", map[string]DirData{
+			"1": {TargetPath: "~/notes", Alias: "a"},
+			"2": {TargetPath: "~/notes", Alias: "b"},
+		}, "duplicate target_path"},
+		{"same expanded path", map[string]DirData{
+			"1": {TargetPath: "~/notes", Alias: "a"},
+			"2": {TargetPath: "/home/u/notes", Alias: "b"},
+		}, "duplicate target_path"},
+		{"same path through a variable", map[string]DirData{
+			"1": {TargetPath: "${XDG_CONFIG_HOME}/app", Alias: "a"},
+			"2": {TargetPath: "~/.config/app", Alias: "b"},
+		}, "duplicate target_path"},
+		{"same alias", map[string]DirData{
+			"1": {TargetPath: "~/a", Alias: "notes"},
+			"2": {TargetPath: "~/b", Alias: "notes"},
+		}, "duplicate alias"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPathEnv(t, "/home/u", nil)
+
+			err := validateDataStoreSchema(&DataStore{CurrentId: 2, TrackedDirs: tt.dirs})
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("validateDataStoreSchema() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validateDataStoreSchema() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
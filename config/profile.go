@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const (
+	DefaultProfile     = "default"
+	DefaultProfilesDir = "profiles"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Profile selected with --profile. Takes precedence over $MMSYNC_PROFILE.
+var selectedProfile string
+
+// SetProfile selects the profile used to resolve the configuration and database paths.
+func SetProfile(name string) error {
+	if name != "" {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+	}
+	selectedProfile = name
+	return nil
+}
+
+// ActiveProfile returns the profile selected with --profile, then $MMSYNC_PROFILE,
+// falling back to the default profile.
+func ActiveProfile() string {
+	if selectedProfile != "" {
+		return selectedProfile
+	}
+	if envProfile := os.Getenv("MMSYNC_PROFILE"); envProfile != "" {
+		return envProfile
+	}
+	return DefaultProfile
+}
+
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s': use letters, digits, '-' and '_' only", name)
+	}
+	return nil
+}
+
+// ConfigDir returns the base configuration directory holding the default
+// profile and the profiles directory.
+func ConfigDir() string {
+	return filepath.Dir(resolveBaseConfigPath())
+}
+
+// ProfileExists reports whether the profile has a configuration file.
+func ProfileExists(name string) bool {
+	_, err := os.Stat(ProfileConfigPath(name))
+	return err == nil
+}
+
+// ListProfiles returns the default profile followed by every named profile, sorted.
+func ListProfiles() ([]string, error) {
+	profiles := []string{DefaultProfile}
+
+	entries, err := os.ReadDir(filepath.Join(ConfigDir(), DefaultProfilesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+
+	var named []string
+	for _, entry := range entries {
+		if entry.IsDir() && profileNamePattern.MatchString(entry.Name()) {
+			named = append(named, entry.Name())
+		}
+	}
+	sort.Strings(named)
+
+	return append(profiles, named...), nil
+}
+
+// DeleteProfile removes the configuration and database of a named profile.
+// The backup repository itself is left untouched.
+func DeleteProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the default profile cannot be deleted")
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+
+	configDir := filepath.Dir(ProfileConfigPath(name))
+	dbPath := ProfileDbPath(name)
+	if _, err := os.Stat(configDir); os.IsNotExist(err) {
+		return fmt.Errorf("profile '%s' does not exist", name)
+	}
+	if err := os.RemoveAll(configDir); err != nil {
+		return fmt.Errorf("failed to remove profile directory %s: %w", configDir, err)
+	}
+	if err := os.Remove(dbPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove profile database %s: %w", dbPath, err)
+	}
+	// Only succeeds once the database directory is empty
+	os.Remove(filepath.Dir(dbPath))
+	return nil
+}
+
+// CopyProfile copies the configuration and database of src into a new profile dst,
+// pointing the copied paths at the new profile directory.
+func CopyProfile(src, dst string) error {
+	if err := ValidateProfileName(dst); err != nil {
+		return err
+	}
+	if !ProfileExists(src) {
+		return fmt.Errorf("profile '%s' does not exist", src)
+	}
+	if _, err := os.Stat(filepath.Dir(ProfileConfigPath(dst))); err == nil {
+		return fmt.Errorf("profile '%s' already exists", dst)
+	}
+
+	srcConfig := ProfileConfigPath(src)
+	dstConfig := ProfileConfigPath(dst)
+	srcDb := ProfileDbPath(src)
+	dstDb := preferredDbPath(dst)
+
+	if err := copyFile(srcConfig, dstConfig); err != nil {
+		return fmt.Errorf("failed to copy configuration file: %w", err)
+	}
+	if _, err := os.Stat(srcDb); err == nil {
+		if err := copyFile(srcDb, dstDb); err != nil {
+			return fmt.Errorf("failed to copy database file: %w", err)
+		}
+	}
+
+	return rewriteConfigPaths(dstConfig, dstDb)
+}
+
+// Points the config_path and db_path recorded in a copied configuration file at its new location.
+func rewriteConfigPaths(configPath string, dbPath string) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("error reading copied config file: %w", err)
+	}
+	cfg, err := configWithPaths(data, configPath, dbPath)
+	if err != nil {
+		return err
+	}
+	return saveConfig(cfg, configPath)
+}
+
+// Parses a configuration file and points its config_path and db_path at configPath and dbPath.
+func configWithPaths(data []byte, configPath string, dbPath string) (*MnemoConf, error) {
+	cfg := GetMnemoConf()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshalling YAML data. File may be invalid: %w", err)
+	}
+	cfg.ConfigSchema.ConfigPath = configPath
+	cfg.ConfigSchema.DbPath = dbPath
+	return cfg, nil
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Limits keeping relocation searches and fingerprints bounded on large trees.
+const (
+	relocateVisitLimit    = 50000
+	fingerprintEntryLimit = 20000
+)
+
+// Fingerprint summarizes a directory as the SHA-256 of each regular file,
+// keyed by its slash-separated path relative to the directory.
+type Fingerprint map[string]string
+
+// FingerprintDir fingerprints the directory at root. Hidden .git and
+// metadata directories are left out, and only the first files found count on
+// very large trees.
+func FingerprintDir(root string) (Fingerprint, error) {
+	fp := make(Fingerprint)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (d.Name() == ".git" || d.Name() == MetadataDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(fp) >= fingerprintEntryLimit {
+			return filepath.SkipAll
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		fp[filepath.ToSlash(rel)] = hash
+		return nil
+	})
+	return fp, err
+}
+
+// Returns the hex SHA-256 of the content of a file.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Similarity returns the share of files present with the same content at the
+// same path in both fingerprints, from 0 for nothing in common to 1 for
+// identical ones.
+func (f Fingerprint) Similarity(other Fingerprint) float64 {
+	if len(f) == 0 && len(other) == 0 {
+		return 0
+	}
+
+	same := 0
+	for path, hash := range f {
+		if otherHash, ok := other[path]; ok && otherHash == hash {
+			same++
+		}
+	}
+	return float64(same) / float64(len(f)+len(other)-same)
+}
+
+// FindRelocated searches roots, up to depth levels deep, for directories named
+// like missingPath that could be where it moved. Repositories, hidden
+// directories and directories that are already tracked are skipped.
+func (c *MnemoConf) FindRelocated(ds *DataStore, missingPath string, roots []string, depth int) []string {
+	name := filepath.Base(missingPath)
+
+	skipped := map[string]bool{missingPath: true}
+	for _, repo := range c.Repositories() {
+		if repo.Path != "" {
+			skipped[repo.Path] = true
+			// The walk may reach a repository by its real path
+			if resolved, err := filepath.EvalSymlinks(repo.Path); err == nil {
+				skipped[resolved] = true
+			}
+		}
+	}
+	for _, entry := range ds.TrackedDirs {
+		if path, err := entry.Path(); err == nil {
+			skipped[path] = true
+		}
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	visited := 0
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if visited++; visited > relocateVisitLimit {
+				return filepath.SkipAll
+			}
+			if seen[path] {
+				return filepath.SkipDir
+			}
+			seen[path] = true
+
+			if path == root {
+				return nil
+			}
+			if skipped[path] || (strings.HasPrefix(d.Name(), ".") && d.Name() != name) {
+				return filepath.SkipDir
+			}
+			if d.Name() == name {
+				candidates = append(candidates, path)
+				return filepath.SkipDir
+			}
+
+			rel, _ := filepath.Rel(root, path)
+			if strings.Count(rel, string(filepath.Separator))+1 >= depth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	return candidates
+}
+
+// RelocateRoots returns the configured relocation roots that exist, expanded
+// to absolute paths. The home directory is used when none are configured.
+func (c *MnemoConf) RelocateRoots() []string {
+	configured := c.ConfigSchema.Relocate.Roots
+	if len(configured) == 0 {
+		configured = []string{"~"}
+	}
+
+	var roots []string
+	for _, root := range configured {
+		expanded, err := ExpandTargetPath(root)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(expanded); err == nil && info.IsDir() {
+			roots = append(roots, expanded)
+		}
+	}
+	return roots
+}
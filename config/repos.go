@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultRepoName is the name under which RepoPath is addressed alongside the
+// repositories listed in ConfigSchema.Repos.
+const DefaultRepoName = "default"
+
+// Repository is a named target repository.
+type Repository struct {
+	Name string
+	Path string
+}
+
+// Repositories returns every configured repository, starting with the one at
+// RepoPath followed by the named repositories sorted by name.
+func (c *MnemoConf) Repositories() []Repository {
+	repos := []Repository{{Name: DefaultRepoName, Path: c.ConfigSchema.RepoPath}}
+
+	names := make([]string, 0, len(c.ConfigSchema.Repos))
+	for name := range c.ConfigSchema.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		repos = append(repos, Repository{Name: name, Path: c.ConfigSchema.Repos[name]})
+	}
+	return repos
+}
+
+// Repository looks up a repository by name.
+func (c *MnemoConf) Repository(name string) (Repository, error) {
+	for _, repo := range c.Repositories() {
+		if repo.Name == name {
+			return repo, nil
+		}
+	}
+	return Repository{}, fmt.Errorf("unknown repository '%s'", name)
+}
+
+// DefaultRepository returns the repository used for entries that do not name one.
+func (c *MnemoConf) DefaultRepository() Repository {
+	if c.ConfigSchema.DefaultRepo != "" {
+		if repo, err := c.Repository(c.ConfigSchema.DefaultRepo); err == nil {
+			return repo
+		}
+	}
+	return Repository{Name: DefaultRepoName, Path: c.ConfigSchema.RepoPath}
+}
+
+// RepositoryFor returns the repository a tracked directory is routed to.
+func (c *MnemoConf) RepositoryFor(data DirData) (Repository, error) {
+	if data.Repo == "" {
+		return Repository{Name: DefaultRepoName, Path: c.ConfigSchema.RepoPath}, nil
+	}
+	return c.Repository(data.Repo)
+}
+
+// AddRepository registers a named repository. The path must contain a .git directory.
+func (c *MnemoConf) AddRepository(name string, path string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return fmt.Errorf("invalid repository name '%s': use letters, digits, '-' and '_' only", name)
+	}
+	if name == DefaultRepoName {
+		return fmt.Errorf("repository name '%s' is reserved for repo_path", DefaultRepoName)
+	}
+	if _, exists := c.ConfigSchema.Repos[name]; exists {
+		return fmt.Errorf("repository '%s' already exists", name)
+	}
+
+	for _, repo := range c.Repositories() {
+		if repo.Path == path {
+			return fmt.Errorf("path '%s' is already configured as repository '%s'", path, repo.Name)
+		}
+	}
+
+	exists, err := GitDirExists(path)
+	if err != nil {
+		return fmt.Errorf("error checking path '%s': %w", path, err)
+	}
+	if !exists {
+		return fmt.Errorf("directory '%s' does not exist", filepath.Join(path, ".git"))
+	}
+
+	if c.ConfigSchema.Repos == nil {
+		c.ConfigSchema.Repos = make(map[string]string)
+	}
+	c.ConfigSchema.Repos[name] = path
+	return nil
+}
+
+// RemoveRepository unregisters a named repository. It refuses while the
+// repository is the default or still has tracked directories routed to it.
+func (c *MnemoConf) RemoveRepository(name string, ds *DataStore) error {
+	if name == DefaultRepoName {
+		return fmt.Errorf("repository '%s' is repo_path and cannot be removed", DefaultRepoName)
+	}
+	if _, exists := c.ConfigSchema.Repos[name]; !exists {
+		return fmt.Errorf("unknown repository '%s'", name)
+	}
+	if c.ConfigSchema.DefaultRepo == name {
+		return fmt.Errorf("repository '%s' is the default repository. Change default_repo first", name)
+	}
+	for id, entry := range ds.TrackedDirs {
+		if entry.Repo == name {
+			return fmt.Errorf("repository '%s' is still used by '%s' (ID: %s)", name, entry.Alias, id)
+		}
+	}
+
+	delete(c.ConfigSchema.Repos, name)
+	return nil
+}
+
+// Reports repositories whose path is missing on disk.
+func checkRepositories(schema *ConfigSchema) error {
+	for name, path := range schema.Repos {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return fmt.Errorf("Repository '%s' does not exist on disk: %s", name, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// ConfigJSONSchema returns a JSON Schema describing the YAML configuration file.
+// Keys are optional since missing ones fall back to their defaults.
+func ConfigJSONSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(MnemoConf{}), "yaml", false)
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "mmsync configuration"
+	schema["required"] = []string{"config_schema"}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// DataStoreJSONSchema returns a JSON Schema describing the JSON database file.
+// Fields without omitempty are required.
+func DataStoreJSONSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(DataStore{}), "json", true)
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "mmsync database"
+
+	trackedDirs := schema["properties"].(map[string]any)["tracked_dirs"].(map[string]any)
+	trackedDirs["propertyNames"] = map[string]any{"pattern": "^[0-9]+$"}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// Builds the schema of a Go type from the field names in tagName. Unexported
+// and untagged fields are left out.
+func typeSchema(t reflect.Type, tagName string, requireAll bool) map[string]any {
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]any)
+		var required []string
+
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := strings.Split(sf.Tag.Get(tagName), ",")
+			if !sf.IsExported() || tag[0] == "" || tag[0] == "-" {
+				continue
+			}
+
+			fieldSchema := typeSchema(sf.Type, tagName, requireAll)
+			if strings.Contains(sf.Tag.Get("mmsync"), "readonly") {
+				fieldSchema["readOnly"] = true
+			}
+			properties[tag[0]] = fieldSchema
+
+			omitEmpty := len(tag) > 1 && tag[1] == "omitempty"
+			if requireAll && !omitEmpty {
+				required = append(required, tag[0])
+			}
+		}
+
+		schema := map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem(), tagName, requireAll),
+		}
+	case reflect.Slice:
+		return map[string]any{
+			"type":  "array",
+			"items": typeSchema(t.Elem(), tagName, requireAll),
+		}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
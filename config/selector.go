@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TagPrefix marks a selector naming a tag, e.g. tag:work.
+const TagPrefix = "tag:"
+
+// ValidateTag checks that a tag uses the same characters as profile names.
+func ValidateTag(tag string) error {
+	if !profileNamePattern.MatchString(tag) {
+		return fmt.Errorf("invalid tag '%s': use letters, digits, '-' and '_' only", tag)
+	}
+	return nil
+}
+
+// HasTag reports whether the entry carries tag.
+func (d DirData) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag adds tag to the entry, keeping the tags sorted. Returns false if it was already there.
+func (d *DirData) AddTag(tag string) bool {
+	if d.HasTag(tag) {
+		return false
+	}
+	d.Tags = append(d.Tags, tag)
+	sort.Strings(d.Tags)
+	return true
+}
+
+// RemoveTag removes tag from the entry. Returns false if it was not there.
+func (d *DirData) RemoveTag(tag string) bool {
+	for i, t := range d.Tags {
+		if t == tag {
+			d.Tags = append(d.Tags[:i], d.Tags[i+1:]...)
+			if len(d.Tags) == 0 {
+				d.Tags = nil
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the IDs of the entries matched by any of the selectors, in ID
+// order. A selector is an ID, an alias, tag:<name>, or a glob matched against
+// the stored and the expanded path. A selector matching nothing is an error.
+func (ds *DataStore) Select(selectors []string) ([]string, error) {
+	selected := make(map[string]bool)
+
+	for _, selector := range selectors {
+		matched := ds.selectOne(selector)
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("no tracked directory matches '%s'", selector)
+		}
+		for _, id := range matched {
+			selected[id] = true
+		}
+	}
+
+	var ids []string
+	for _, id := range ds.IDs() {
+		if selected[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Matches an exact ID or alias first, and only then tags and path globs.
+func (ds *DataStore) selectOne(selector string) []string {
+	if _, ok := ds.TrackedDirs[selector]; ok {
+		return []string{selector}
+	}
+	for _, id := range ds.IDs() {
+		if ds.TrackedDirs[id].Alias == selector {
+			return []string{id}
+		}
+	}
+
+	var matched []string
+	tag, isTag := strings.CutPrefix(selector, TagPrefix)
+	for _, id := range ds.IDs() {
+		entry := ds.TrackedDirs[id]
+		if isTag && entry.HasTag(tag) || !isTag && matchesPath(selector, entry) {
+			matched = append(matched, id)
+		}
+	}
+	return matched
+}
+
+func matchesPath(pattern string, entry DirData) bool {
+	if !strings.ContainsAny(pattern, "/*?[") {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern, entry.TargetPath); ok {
+		return true
+	}
+
+	path, err := entry.Path()
+	if err != nil {
+		return false
+	}
+	if expanded, err := ExpandTargetPath(pattern); err == nil {
+		pattern = expanded
+	} else if abs, err := filepath.Abs(pattern); err == nil {
+		pattern = abs
+	}
+	ok, _ := filepath.Match(pattern, path)
+	return ok
+}
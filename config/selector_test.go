@@ -0,0 +1,63 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	ds := &DataStore{CurrentId: 4, TrackedDirs: map[string]DirData{
+		"1": {TargetPath: "~/notes", Alias: "notes", Tags: []string{"work"}},
+		"2": {TargetPath: "~/2", Alias: "two", Tags: []string{"home", "work"}},
+		"3": {TargetPath: "/srv/notes", Alias: "1"},
+		"4": {TargetPath: "~/projects/app", Alias: "app"},
+	}}
+
+	tests := []struct {
+		name      string
+		selectors []string
+		want      []string
+		wantErr   bool
+	}{
+		{"ID", []string{"2"}, []string{"2"}, false},
+		{"ID before an alias of the same name", []string{"1"}, []string{"1"}, false},
+		{"alias", []string{"app"}, []string{"4"}, false},
+		{"tag", []string{"tag:work"}, []string{"1", "2"}, false},
+		{"unknown tag", []string{"tag:none"}, nil, true},
+		{"stored path glob", []string{"~/*"}, []string{"1", "2"}, false},
+		{"expanded path glob", []string{"/home/u/projects/*"}, []string{"4"}, false},
+		{"absolute glob", []string{"/*/notes"}, []string{"3"}, false},
+		{"several selectors in ID order", []string{"app", "two"}, []string{"2", "4"}, false},
+		{"overlapping selectors", []string{"tag:work", "notes"}, []string{"1", "2"}, false},
+		{"no match", []string{"missing"}, nil, true},
+		{"one selector without a match", []string{"app", "missing"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPathEnv(t, "/home/u", nil)
+
+			got, err := ds.Select(tt.selectors)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select(%q) error = %v, want error %v", tt.selectors, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select(%q) = %v, want %v", tt.selectors, got, tt.want)
+			}
+		})
+	}
+}
+
+// An exact alias wins over path globs, whichever ID comes first.
+func TestSelectAliasBeforeGlob(t *testing.T) {
+	ds := &DataStore{CurrentId: 2, TrackedDirs: map[string]DirData{
+		"1": {TargetPath: "/srv/a*b", Alias: "first"},
+		"2": {TargetPath: "/srv/other", Alias: "/srv/a*b"},
+	}}
+	setPathEnv(t, "/home/u", nil)
+
+	got := ds.selectOne("/srv/a*b")
+	if want := []string{"2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selectOne() = %v, want %v", got, want)
+	}
+}
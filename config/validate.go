@@ -0,0 +1,221 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is a single problem found in a configuration file, at the
+// position of the offending YAML node.
+type ValidationIssue struct {
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Severity, i.Message)
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// ValidateConfigFile reports every problem in the configuration file at path
+// without changing it. The error is only set when the file cannot be read.
+func ValidateConfigFile(path string) ([]ValidationIssue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	return ValidateConfigData(data), nil
+}
+
+// ValidateConfigData reports every problem in raw configuration data: syntax errors,
+// unknown keys, values of the wrong type and values failing the healing rules.
+func ValidateConfigData(data []byte) []ValidationIssue {
+	var issues []ValidationIssue
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		issue := ValidationIssue{Line: 1, Column: 1, Severity: SeverityError, Message: err.Error()}
+		if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+		}
+		return append(issues, issue)
+	}
+
+	if len(root.Content) == 0 {
+		return append(issues, ValidationIssue{Line: 1, Column: 1, Severity: SeverityError, Message: "configuration file is empty"})
+	}
+
+	doc := root.Content[0]
+	checker := &nodeChecker{positions: make(map[string]*yaml.Node), invalid: make(map[string]bool)}
+	checker.check(doc, reflect.TypeOf(MnemoConf{}), "")
+	issues = checker.issues
+	positions := checker.positions
+
+	schemaNode, ok := positions["config_schema"]
+	if !ok {
+		return append(issues, issueAt(doc, SeverityError, "missing required key 'config_schema'"))
+	}
+
+	cfg := GetMnemoConf()
+	// Type errors were reported above; decode whatever is valid for the rule checks
+	_ = doc.Decode(cfg)
+	schema := &cfg.ConfigSchema
+
+	if _, set := positions["config_schema.schema_version"]; !set {
+		schema.SchemaVersion = 0
+	}
+	if schema.SchemaVersion > CurrentSchemaVersion {
+		issues = append(issues, issueAt(nodeFor(positions, "schema_version", schemaNode), SeverityError,
+			fmt.Sprintf("schema_version %d is newer than supported version %d", schema.SchemaVersion, CurrentSchemaVersion)))
+	} else if schema.SchemaVersion < CurrentSchemaVersion {
+		issues = append(issues, issueAt(nodeFor(positions, "schema_version", schemaNode), SeverityWarning,
+			fmt.Sprintf("schema_version %d will be migrated to %d on next load", schema.SchemaVersion, CurrentSchemaVersion)))
+	}
+
+	// Without a valid is_init it is unknown which rules apply
+	if checker.invalid["config_schema.is_init"] {
+		return issues
+	}
+	if !schema.IsInit {
+		issues = append(issues, issueAt(nodeFor(positions, "is_init", schemaNode), SeverityWarning,
+			"is_init is false; repo_path and db_path will be reset on load"))
+		return issues
+	}
+
+	for _, rule := range configFieldRules {
+		if checker.invalid["config_schema."+rule.key] {
+			continue
+		}
+		if err := rule.check(schema); err != nil {
+			severity := SeverityError
+			if !rule.repairable {
+				severity = SeverityWarning
+			}
+			issues = append(issues, issueAt(nodeFor(positions, rule.key, schemaNode), severity,
+				fmt.Sprintf("%s: %v", rule.key, err)))
+		}
+	}
+
+	return issues
+}
+
+// Walks a YAML document alongside the Go type it decodes into.
+type nodeChecker struct {
+	// Key node of every known key path
+	positions map[string]*yaml.Node
+	// Key paths whose value failed to decode
+	invalid map[string]bool
+	issues  []ValidationIssue
+}
+
+func (c *nodeChecker) report(node *yaml.Node, keyPath string, message string) {
+	c.issues = append(c.issues, issueAt(node, SeverityError, message))
+	if keyPath != "" {
+		c.invalid[keyPath] = true
+	}
+}
+
+func (c *nodeChecker) check(node *yaml.Node, t reflect.Type, keyPath string) {
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			c.report(node, keyPath, fmt.Sprintf("%s must be a mapping", describeKey(keyPath)))
+			return
+		}
+		known := make(map[string]reflect.StructField)
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if name := strings.Split(sf.Tag.Get("yaml"), ",")[0]; sf.IsExported() && name != "-" && name != "" {
+				known[name] = sf
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			childPath := keyNode.Value
+			if keyPath != "" {
+				childPath = keyPath + "." + keyNode.Value
+			}
+
+			sf, ok := known[keyNode.Value]
+			if !ok {
+				c.report(keyNode, "", fmt.Sprintf("unknown key '%s'", describeKey(childPath)))
+				continue
+			}
+			if _, seen := c.positions[childPath]; seen {
+				c.report(keyNode, "", fmt.Sprintf("duplicate key '%s'", describeKey(childPath)))
+				continue
+			}
+			c.positions[childPath] = keyNode
+			c.check(valueNode, sf.Type, childPath)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			c.report(node, keyPath, fmt.Sprintf("%s must be a mapping", describeKey(keyPath)))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			c.check(node.Content[i+1], t.Elem(), keyPath+"."+node.Content[i].Value)
+		}
+	default:
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			c.report(node, keyPath, fmt.Sprintf("%s: expected %s, got %s", describeKey(keyPath), typeName(t), describeNode(node)))
+		}
+	}
+}
+
+// Strips the config_schema prefix so messages use the same keys as `config get`.
+func describeKey(keyPath string) string {
+	if keyPath == "" {
+		return "document"
+	}
+	return strings.TrimPrefix(keyPath, "config_schema.")
+}
+
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.MappingNode:
+		return "a mapping"
+	default:
+		return "'" + node.Value + "'"
+	}
+}
+
+func nodeFor(positions map[string]*yaml.Node, key string, fallback *yaml.Node) *yaml.Node {
+	if node, ok := positions["config_schema."+key]; ok {
+		return node
+	}
+	return fallback
+}
+
+func issueAt(node *yaml.Node, severity string, message string) ValidationIssue {
+	return ValidationIssue{Line: node.Line, Column: node.Column, Severity: severity, Message: message}
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Slice:
+		return "a list"
+	default:
+		return "a " + t.Kind().String()
+	}
+}
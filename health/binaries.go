@@ -0,0 +1,155 @@
+package health
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Binary is an external program mmsync runs, with how to read its version and
+// the oldest version supporting the flags mmsync relies on.
+type Binary struct {
+	Name     string
+	Severity Severity
+	// VersionArgs make the binary print its version.
+	VersionArgs []string
+	// VersionPattern captures the version in its first group.
+	VersionPattern *regexp.Regexp
+	// Minimum is the oldest supported version, empty when any version works.
+	Minimum string
+}
+
+// Binaries are the external programs checked by `mmsync health`.
+var Binaries = []Binary{
+	// GIT_SSH_COMMAND, used to keep remote checks from prompting, needs git 2.3.
+	{
+		Name:           "git",
+		Severity:       SeverityCritical,
+		VersionArgs:    []string{"--version"},
+		VersionPattern: regexp.MustCompile(`git version (\d+(?:\.\d+)*)`),
+		Minimum:        "2.3",
+	},
+	// Nothing runs rsync yet, so a missing one is only a warning and any
+	// version works.
+	{
+		Name:           "rsync",
+		Severity:       SeverityWarning,
+		VersionArgs:    []string{"--version"},
+		VersionPattern: regexp.MustCompile(`rsync\s+version\s+v?(\d+(?:\.\d+)*)`),
+	},
+	{
+		Name:           "tar",
+		Severity:       SeverityCritical,
+		VersionArgs:    []string{"--version"},
+		VersionPattern: regexp.MustCompile(`(?:\(GNU tar\)|bsdtar)\s+(\d+(?:\.\d+)*)`),
+	},
+	// zip has no --version; -v prints its version banner.
+	{
+		Name:           "zip",
+		Severity:       SeverityWarning,
+		VersionArgs:    []string{"-v"},
+		VersionPattern: regexp.MustCompile(`This is Zip (\d+(?:\.\d+)*)`),
+	},
+}
+
+// LookupBinary returns the known binary with the given name.
+func LookupBinary(name string) (Binary, bool) {
+	for _, b := range Binaries {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Binary{}, false
+}
+
+// BinaryNotFoundError is returned by Probe when the binary is not in PATH.
+type BinaryNotFoundError struct {
+	Name string
+}
+
+func (e *BinaryNotFoundError) Error() string {
+	return fmt.Sprintf("'%s' not found in PATH", e.Name)
+}
+
+// Probe locates the binary and reads its version. The path is returned even
+// when the version cannot be read.
+func (b Binary) Probe() (string, string, error) {
+	path, err := exec.LookPath(b.Name)
+	if err != nil {
+		return "", "", &BinaryNotFoundError{Name: b.Name}
+	}
+
+	output, err := exec.Command(path, b.VersionArgs...).CombinedOutput()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return path, "", fmt.Errorf("%s %s: exit code %d: %s", b.Name, strings.Join(b.VersionArgs, " "), exitError.ExitCode(), firstLine(output))
+		}
+		return path, "", fmt.Errorf("%s %s: %w", b.Name, strings.Join(b.VersionArgs, " "), err)
+	}
+
+	match := b.VersionPattern.FindSubmatch(output)
+	if match == nil {
+		return path, "", fmt.Errorf("no version found in the output of %s %s: %s", b.Name, strings.Join(b.VersionArgs, " "), firstLine(output))
+	}
+	return path, string(match[1]), nil
+}
+
+// Supported reports whether version meets the binary's minimum.
+func (b Binary) Supported(version string) bool {
+	return b.Minimum == "" || CompareVersions(version, b.Minimum) >= 0
+}
+
+// Require checks that the binary is installed and recent enough.
+func (b Binary) Require() error {
+	_, version, err := b.Probe()
+	if err != nil {
+		return err
+	}
+	if !b.Supported(version) {
+		return fmt.Errorf("%s %s is installed, but mmsync needs %s or newer", b.Name, version, b.Minimum)
+	}
+	return nil
+}
+
+// RequireBinaries checks every named binary, reporting the first one missing
+// or too old.
+func RequireBinaries(names ...string) error {
+	for _, name := range names {
+		b, ok := LookupBinary(name)
+		if !ok {
+			return fmt.Errorf("unknown binary '%s'", name)
+		}
+		if err := b.Require(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompareVersions compares dotted version numbers, returning -1, 0 or 1.
+// Missing components count as zero, so 2.3 equals 2.3.0.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+func firstLine(output []byte) string {
+	return strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+}
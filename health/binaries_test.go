@@ -0,0 +1,52 @@
+package health
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.3", "2.3", 0},
+		{"2.3", "2.3.0", 0},
+		{"2.3.0.0", "2.3", 0},
+		{"2.10", "2.9", 1},
+		{"2.9", "2.10", -1},
+		{"2.43.0", "2.3", 1},
+		{"1.9.5", "2.3", -1},
+		{"3", "2.99.99", 1},
+		{"2.3.1", "2.3", 1},
+		{"2", "2.0.1", -1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	git, ok := LookupBinary("git")
+	if !ok {
+		t.Fatal("git is not a known binary")
+	}
+
+	tests := []struct {
+		binary  Binary
+		version string
+		want    bool
+	}{
+		{git, "2.3", true},
+		{git, "2.43.0", true},
+		{git, "2.2.9", false},
+		{git, "1.9", false},
+		{Binary{Name: "tar"}, "0.1", true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.binary.Supported(tt.version); got != tt.want {
+			t.Errorf("%s.Supported(%q) = %v, want %v", tt.binary.Name, tt.version, got, tt.want)
+		}
+	}
+}
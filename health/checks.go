@@ -0,0 +1,166 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bladeacer/mmsync/config"
+)
+
+func init() {
+	for _, b := range Binaries {
+		Register(binaryCheck(b))
+	}
+	Register(NewCheck("config/file", SeverityCritical, checkConfigFile))
+	Register(NewCheck("config/permissions", SeverityWarning, checkConfigPermissions))
+	Register(NewCheck("repo/paths", SeverityCritical, checkRepoPaths))
+	Register(NewCheck("database/file", SeverityWarning, checkDatabaseFile))
+	Register(NewCheck("tracked/paused", SeverityInfo, checkPaused))
+}
+
+// Returns the command initializing the profile being checked.
+func initCommand(ctx *Context) string {
+	if ctx.Profile == config.DefaultProfile {
+		return "mmsync init"
+	}
+	return fmt.Sprintf("mmsync --profile %s init", ctx.Profile)
+}
+
+func binaryCheck(b Binary) Check {
+	return NewCheck("binary/"+b.Name, b.Severity, func(ctx *Context) Result {
+		path, version, err := b.Probe()
+		var notFound *BinaryNotFoundError
+		switch {
+		case errors.As(err, &notFound):
+			return Result{
+				Status:  StatusFail,
+				Message: err.Error(),
+				Hint:    fmt.Sprintf("Install %s with your package manager.", b.Name),
+			}
+		case err != nil:
+			return Result{
+				Status:  StatusWarn,
+				Message: fmt.Sprintf("found at %s, but the version check failed", path),
+				Details: []string{err.Error()},
+			}
+		case !b.Supported(version):
+			return Result{
+				Status:  StatusFail,
+				Message: fmt.Sprintf("found at %s, version %s is older than the required %s", path, version, b.Minimum),
+				Hint:    fmt.Sprintf("Upgrade %s to %s or newer.", b.Name, b.Minimum),
+			}
+		}
+
+		message := fmt.Sprintf("found at %s (version %s)", path, version)
+		if b.Minimum != "" {
+			message = fmt.Sprintf("found at %s (version %s, %s or newer required)", path, version, b.Minimum)
+		}
+		return Result{Status: StatusPass, Message: message}
+	})
+}
+
+func checkConfigFile(ctx *Context) Result {
+	configPath := ctx.Config.ConfigSchema.ConfigPath
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return Result{
+			Status:  StatusFail,
+			Message: fmt.Sprintf("configuration file not found at %s", configPath),
+			Hint:    fmt.Sprintf("Run '%s' to start.", initCommand(ctx)),
+		}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("found at %s", configPath)}
+}
+
+func checkConfigPermissions(ctx *Context) Result {
+	configPath := ctx.Config.ConfigSchema.ConfigPath
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return Result{Status: StatusSkip, Message: "configuration file not found"}
+	}
+
+	mode := info.Mode().Perm()
+	if mode&0022 == 0 {
+		return Result{Status: StatusPass, Message: fmt.Sprintf("%s has mode %04o", configPath, mode)}
+	}
+
+	fixed := mode &^ 0022
+	return Result{
+		Status:  StatusFail,
+		Message: fmt.Sprintf("%s has mode %04o and is writable by other users", configPath, mode),
+		Hint:    fmt.Sprintf("Run chmod go-w %s.", configPath),
+		Fixes: []Fix{{
+			Description: fmt.Sprintf("Change the mode of %s to %04o", configPath, fixed),
+			Apply:       func() error { return os.Chmod(configPath, fixed) },
+		}},
+	}
+}
+
+func checkRepoPaths(ctx *Context) Result {
+	if !ctx.Config.ConfigSchema.IsInit {
+		return Result{Status: StatusSkip, Message: "profile is not initialized"}
+	}
+
+	result := Result{Status: StatusPass}
+	missing := 0
+	for _, repo := range ctx.Config.Repositories() {
+		if repo.Path == "" {
+			result.Status = StatusFail
+			result.Details = append(result.Details, fmt.Sprintf("%s: path is not set", repo.Name))
+			result.Hint = fmt.Sprintf("Run '%s' to set it.", initCommand(ctx))
+			continue
+		}
+		if _, err := os.Stat(repo.Path); os.IsNotExist(err) {
+			missing++
+			result.Details = append(result.Details, fmt.Sprintf("%s: %s does not exist on disk", repo.Name, repo.Path))
+			continue
+		}
+		result.Details = append(result.Details, fmt.Sprintf("%s: %s", repo.Name, repo.Path))
+	}
+
+	if missing > 0 && result.Status == StatusPass {
+		result.Status = StatusWarn
+		result.Hint = "Restore the repository, or point the configuration at its new location."
+	}
+	result.Message = fmt.Sprintf("%d repositories configured, %d missing", len(ctx.Config.Repositories()), missing)
+	return result
+}
+
+func checkDatabaseFile(ctx *Context) Result {
+	dbPath := ctx.Config.ConfigSchema.DbPath
+	if dbPath == "" {
+		return Result{Status: StatusFail, Message: "database path is not defined", Hint: fmt.Sprintf("Run '%s' to start.", initCommand(ctx))}
+	}
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return Result{
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("database file not found at %s", dbPath),
+			Hint:    "It is created by init and whenever tracking changes, e.g. with mmsync add.",
+			Fixes: []Fix{{
+				Description: fmt.Sprintf("Create the database at %s", dbPath),
+				Apply:       func() error { return ctx.Data.SaveData(dbPath) },
+			}},
+		}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("found at %s", dbPath)}
+}
+
+func checkPaused(ctx *Context) Result {
+	now := time.Now()
+	result := Result{Status: StatusPass}
+
+	paused := 0
+	for _, id := range ctx.Data.IDs() {
+		entry := ctx.Data.TrackedDirs[id]
+		if entry.Paused {
+			result.Details = append(result.Details, fmt.Sprintf("%s (ID: %s): %s", entry.Alias, id, entry.PauseStatus(now)))
+			if entry.IsPaused(now) {
+				paused++
+			}
+		}
+	}
+
+	result.Message = fmt.Sprintf("%d tracked, %d paused", len(ctx.Data.TrackedDirs), paused)
+	return result
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package health
+
+import "errors"
+
+func freeSpace(path string) (uint64, error) {
+	return 0, errors.New("free space cannot be read on this platform")
+}
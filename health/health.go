@@ -0,0 +1,208 @@
+// Package health defines the checks run by `mmsync health` and the registry
+// they are added to.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/bladeacer/mmsync/config"
+)
+
+// Status is the outcome of a check, ordered from best to worst.
+type Status int
+
+const (
+	StatusSkip Status = iota
+	StatusPass
+	StatusWarn
+	StatusFail
+)
+
+var statusNames = map[Status]string{
+	StatusSkip: "skip",
+	StatusPass: "pass",
+	StatusWarn: "warn",
+	StatusFail: "fail",
+}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// ExitCode maps the worst status of a run to the process exit code:
+// 0 when everything passed, 1 for warnings and 2 for failures.
+func (s Status) ExitCode() int {
+	switch s {
+	case StatusFail:
+		return 2
+	case StatusWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Severity is how much a failing check matters. A failing check of warning
+// severity is reported as a warning.
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+// Context is what checks inspect.
+type Context struct {
+	Profile string
+	Config  *config.MnemoConf
+	Data    *config.DataStore
+}
+
+// Result is what a check found. Hint tells the user how to fix a problem and
+// Details holds one line per item inspected, where that helps. Fixes are the
+// repairs `mmsync doctor --fix` can apply for it.
+type Result struct {
+	Status  Status
+	Message string
+	Hint    string
+	Details []string
+	Fixes   []Fix
+}
+
+// Fix is a repair for a problem found by a check.
+type Fix struct {
+	Description string       `json:"description"`
+	Apply       func() error `json:"-"`
+}
+
+// Check is a single health check.
+type Check interface {
+	// Name identifies the check as <group>/<name>, e.g. binary/git.
+	Name() string
+	Severity() Severity
+	Run(ctx *Context) Result
+}
+
+type funcCheck struct {
+	name     string
+	severity Severity
+	run      func(ctx *Context) Result
+}
+
+func (c funcCheck) Name() string            { return c.name }
+func (c funcCheck) Severity() Severity      { return c.severity }
+func (c funcCheck) Run(ctx *Context) Result { return c.run(ctx) }
+
+// NewCheck wraps a function as a Check.
+func NewCheck(name string, severity Severity, run func(ctx *Context) Result) Check {
+	return funcCheck{name: name, severity: severity, run: run}
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []Check
+)
+
+// Register adds a check to the ones run by `mmsync health`. Checks run in
+// registration order. Registering a name twice replaces the earlier check.
+func Register(c Check) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for i, existing := range registry {
+		if existing.Name() == c.Name() {
+			registry[i] = c
+			return
+		}
+	}
+	registry = append(registry, c)
+}
+
+// Checks returns the registered checks in registration order.
+func Checks() []Check {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return append([]Check(nil), registry...)
+}
+
+// CheckReport is the result of one check in a Report.
+type CheckReport struct {
+	Name     string   `json:"name"`
+	Severity Severity `json:"severity"`
+	Status   Status   `json:"status"`
+	Message  string   `json:"message"`
+	Hint     string   `json:"hint,omitempty"`
+	Details  []string `json:"details,omitempty"`
+	Fixes    []Fix    `json:"fixes,omitempty"`
+}
+
+// Report is the outcome of running every registered check.
+type Report struct {
+	Profile string        `json:"profile"`
+	Status  Status        `json:"status"`
+	Checks  []CheckReport `json:"checks"`
+}
+
+// Run runs every registered check. A panicking check is reported as failed.
+func Run(ctx *Context) Report {
+	report := Report{Profile: ctx.Profile, Status: StatusPass}
+
+	for _, c := range Checks() {
+		result := runCheck(c, ctx)
+		if result.Status == StatusFail && c.Severity() != SeverityCritical {
+			result.Status = StatusWarn
+		}
+		if result.Status > report.Status {
+			report.Status = result.Status
+		}
+
+		report.Checks = append(report.Checks, CheckReport{
+			Name:     c.Name(),
+			Severity: c.Severity(),
+			Status:   result.Status,
+			Message:  result.Message,
+			Hint:     result.Hint,
+			Details:  result.Details,
+			Fixes:    result.Fixes,
+		})
+	}
+
+	return report
+}
+
+func runCheck(c Check, ctx *Context) (result Result) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = Result{Status: StatusFail, Message: fmt.Sprintf("check panicked: %v", r)}
+		}
+	}()
+	return c.Run(ctx)
+}
+
+// Counts returns the number of checks with each status.
+func (r Report) Counts() map[Status]int {
+	counts := make(map[Status]int)
+	for _, c := range r.Checks {
+		counts[c.Status]++
+	}
+	return counts
+}
+
+// Fixes returns the fixes offered by checks that did not pass, in check order.
+func (r Report) Fixes() []Fix {
+	var fixes []Fix
+	for _, c := range r.Checks {
+		if c.Status > StatusPass {
+			fixes = append(fixes, c.Fixes...)
+		}
+	}
+	return fixes
+}
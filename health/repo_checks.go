@@ -0,0 +1,455 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bladeacer/mmsync/config"
+)
+
+// How long a remote may take to answer before it counts as unreachable.
+const remoteTimeout = 15 * time.Second
+
+func init() {
+	Register(NewCheck("repo/worktree", SeverityCritical, forEachRepo(checkWorkTree)))
+	Register(NewCheck("repo/writable", SeverityCritical, forEachRepo(checkWritable)))
+	Register(NewCheck("git/identity", SeverityCritical, forEachRepo(checkIdentity)))
+	Register(NewCheck("repo/remote", SeverityWarning, forEachRepo(checkRemote)))
+	Register(NewCheck("repo/orphans", SeverityWarning, checkOrphans))
+	Register(NewCheck("disk/space", SeverityWarning, checkDiskSpace))
+	Register(NewCheck("tracked/paths", SeverityWarning, checkTrackedPaths))
+	Register(NewCheck("tracked/stale", SeverityWarning, checkStale))
+}
+
+// Runs git in repoPath without prompting for credentials.
+func git(ctx context.Context, repoPath string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoPath}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	output, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(output))
+	if err != nil && out != "" {
+		return out, fmt.Errorf("%w: %s", err, out)
+	}
+	return out, err
+}
+
+// Wraps a fix that runs git so that it fails early when git is missing or too
+// old, leaving the other fixes to apply.
+func withGit(apply func() error) func() error {
+	return func() error {
+		if err := RequireBinaries("git"); err != nil {
+			return err
+		}
+		return apply()
+	}
+}
+
+// Builds a check running repoCheck on every configured repository that exists.
+// The worst status wins, and each repository adds one detail line from the
+// message of its result.
+func forEachRepo(repoCheck func(ctx *Context, repo config.Repository) Result) func(ctx *Context) Result {
+	return func(ctx *Context) Result {
+		if !ctx.Config.ConfigSchema.IsInit {
+			return Result{Status: StatusSkip, Message: "profile is not initialized"}
+		}
+		if _, err := exec.LookPath("git"); err != nil {
+			return Result{Status: StatusSkip, Message: "git is not installed"}
+		}
+
+		result := Result{Status: StatusSkip}
+		checked, failed := 0, 0
+		for _, repo := range ctx.Config.Repositories() {
+			if _, err := os.Stat(repo.Path); repo.Path == "" || err != nil {
+				continue
+			}
+
+			repoResult := repoCheck(ctx, repo)
+			result.Details = append(result.Details, fmt.Sprintf("%s: %s", repo.Name, repoResult.Message))
+			result.Fixes = append(result.Fixes, repoResult.Fixes...)
+			if repoResult.Status > result.Status {
+				result.Status = repoResult.Status
+			}
+			if repoResult.Status > StatusPass {
+				failed++
+				if result.Hint == "" {
+					result.Hint = repoResult.Hint
+				}
+			}
+			if repoResult.Status != StatusSkip {
+				checked++
+			}
+		}
+
+		result.Message = fmt.Sprintf("%d of %d repositories OK", checked-failed, checked)
+		if checked == 0 {
+			result.Message = "no repository to check"
+		}
+		return result
+	}
+}
+
+func checkWorkTree(ctx *Context, repo config.Repository) Result {
+	out, err := git(context.Background(), repo.Path, "rev-parse", "--is-inside-work-tree", "--show-toplevel")
+	lines := strings.Split(out, "\n")
+	if err != nil || lines[0] != "true" {
+		return Result{
+			Status:  StatusFail,
+			Message: "not a git work tree",
+			Hint:    fmt.Sprintf("Run 'git init' in %s, or clone the repository there.", repo.Path),
+		}
+	}
+	if len(lines) > 1 && !samePath(lines[1], repo.Path) {
+		return Result{
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("inside the work tree of %s", lines[1]),
+			Hint:    "Point the configuration at the top of the work tree.",
+		}
+	}
+	return Result{Status: StatusPass, Message: "valid work tree"}
+}
+
+// Reports whether a and b name the same directory once symbolic links are
+// resolved, e.g. /var and /private/var on macOS.
+func samePath(a string, b string) bool {
+	if a == b {
+		return true
+	}
+	ra, errA := filepath.EvalSymlinks(a)
+	rb, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && ra == rb
+}
+
+func checkWritable(ctx *Context, repo config.Repository) Result {
+	probe, err := os.CreateTemp(repo.Path, ".mmsync-write-test-*")
+	if err != nil {
+		return Result{
+			Status:  StatusFail,
+			Message: fmt.Sprintf("not writable: %v", err),
+			Hint:    fmt.Sprintf("Fix the ownership or permissions of %s.", repo.Path),
+		}
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return Result{Status: StatusPass, Message: "writable"}
+}
+
+func checkIdentity(ctx *Context, repo config.Repository) Result {
+	if _, err := git(context.Background(), repo.Path, "rev-parse", "--git-dir"); err != nil {
+		return Result{Status: StatusSkip, Message: "not a git work tree"}
+	}
+
+	name, email := defaultIdentity()
+	values := map[string]string{"user.name": name, "user.email": email}
+
+	result := Result{Status: StatusPass, Message: "user.name and user.email set"}
+	var missing []string
+	for _, key := range []string{"user.name", "user.email"} {
+		if value, err := git(context.Background(), repo.Path, "config", "--get", key); err == nil && value != "" {
+			continue
+		}
+		missing = append(missing, key)
+
+		if value := values[key]; value != "" {
+			result.Fixes = append(result.Fixes, Fix{
+				Description: fmt.Sprintf("Set %s to '%s' in %s", key, value, repo.Path),
+				Apply: withGit(func() error {
+					_, err := git(context.Background(), repo.Path, "config", key, value)
+					return err
+				}),
+			})
+		}
+	}
+
+	if len(missing) > 0 {
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("%s not set", strings.Join(missing, " and "))
+		result.Hint = "Set them with git config --global user.name <name> and git config --global user.email <email>."
+	}
+	return result
+}
+
+// Returns the identity doctor offers to configure: $GIT_AUTHOR_NAME and
+// $GIT_AUTHOR_EMAIL when set, otherwise one derived from the user account.
+func defaultIdentity() (string, string) {
+	name, email := os.Getenv("GIT_AUTHOR_NAME"), os.Getenv("GIT_AUTHOR_EMAIL")
+
+	account, err := user.Current()
+	if err != nil {
+		return name, email
+	}
+	if name == "" {
+		name = account.Name
+		if name == "" {
+			name = account.Username
+		}
+	}
+	if email == "" {
+		if host, err := os.Hostname(); err == nil && account.Username != "" {
+			email = fmt.Sprintf("%s@%s", account.Username, host)
+		}
+	}
+	return name, email
+}
+
+func checkRemote(ctx *Context, repo config.Repository) Result {
+	out, err := git(context.Background(), repo.Path, "remote")
+	if err != nil {
+		return Result{Status: StatusSkip, Message: "remotes could not be listed"}
+	}
+	remotes := strings.Fields(out)
+	if len(remotes) == 0 {
+		return Result{
+			Status:  StatusWarn,
+			Message: "no remote configured",
+			Hint:    fmt.Sprintf("Add one with git -C %s remote add origin <url>.", repo.Path),
+		}
+	}
+
+	remote := remotes[0]
+	for _, r := range remotes {
+		if r == "origin" {
+			remote = r
+		}
+	}
+
+	timeout, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	if _, err := git(timeout, repo.Path, "ls-remote", "--heads", remote); err != nil {
+		return Result{
+			Status:  StatusFail,
+			Message: fmt.Sprintf("remote '%s' is unreachable", remote),
+			Hint:    "Check the remote URL, the network and your credentials.",
+		}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("remote '%s' reachable", remote)}
+}
+
+func checkOrphans(ctx *Context) Result {
+	if !ctx.Config.ConfigSchema.IsInit {
+		return Result{Status: StatusSkip, Message: "profile is not initialized"}
+	}
+
+	result := Result{Status: StatusPass}
+	count := 0
+	for _, repo := range ctx.Config.Repositories() {
+		if _, err := os.Stat(repo.Path); repo.Path == "" || err != nil {
+			continue
+		}
+		orphans, err := ctx.Config.Orphans(repo, ctx.Data)
+		if err != nil {
+			result.Details = append(result.Details, fmt.Sprintf("%s: %v", repo.Name, err))
+			continue
+		}
+
+		count += len(orphans)
+		for _, orphan := range orphans {
+			result.Details = append(result.Details, fmt.Sprintf("%s: %s", repo.Name, orphan.Name))
+			result.Fixes = append(result.Fixes, Fix{
+				Description: fmt.Sprintf("Remove orphaned %s", orphan.Path),
+				Apply:       withGit(orphan.Remove),
+			})
+		}
+	}
+
+	result.Message = fmt.Sprintf("%d orphaned entries", count)
+	if count > 0 {
+		result.Status = StatusWarn
+		result.Hint = "They mirror no tracked directory. Review them with mmsync clean, or list names to keep in clean.keep."
+	}
+	return result
+}
+
+func checkDiskSpace(ctx *Context) Result {
+	if !ctx.Config.ConfigSchema.IsInit {
+		return Result{Status: StatusSkip, Message: "profile is not initialized"}
+	}
+
+	// Each repository only has to hold the entries routed to it
+	totals := make(map[string]int64)
+	for _, entry := range ctx.Data.TrackedDirs {
+		repo, err := ctx.Config.RepositoryFor(entry)
+		if err != nil {
+			continue
+		}
+		if path, err := entry.Path(); err == nil {
+			totals[repo.Name] += config.DirectorySize(path, 0)
+		}
+	}
+
+	result := Result{Status: StatusSkip}
+	checked, short := 0, 0
+	for _, repo := range ctx.Config.Repositories() {
+		if _, err := os.Stat(repo.Path); repo.Path == "" || err != nil {
+			continue
+		}
+		free, err := freeSpace(repo.Path)
+		if err != nil {
+			result.Details = append(result.Details, fmt.Sprintf("%s: free space unknown: %v", repo.Name, err))
+			continue
+		}
+
+		checked++
+		result.Details = append(result.Details, fmt.Sprintf("%s: %s free for %s of tracked directories", repo.Name, formatBytes(int64(free)), formatBytes(totals[repo.Name])))
+		if uint64(totals[repo.Name]) > free {
+			short++
+		}
+	}
+
+	switch {
+	case checked == 0:
+		result.Message = "free space unknown"
+	case short > 0:
+		result.Status = StatusFail
+		result.Message = fmt.Sprintf("%d of %d repositories lack space for their tracked directories", short, checked)
+		result.Hint = "Free up space on the repository's disk, or pause large entries."
+	default:
+		result.Status = StatusPass
+		result.Message = fmt.Sprintf("%d of %d repositories have room", checked, checked)
+	}
+	return result
+}
+
+func checkTrackedPaths(ctx *Context) Result {
+	result := Result{Status: StatusPass}
+	problems := 0
+
+	for _, id := range ctx.Data.IDs() {
+		entry := ctx.Data.TrackedDirs[id]
+
+		path, err := entry.Path()
+		if err == nil {
+			var dir *os.File
+			if dir, err = os.Open(path); err == nil {
+				_, err = dir.Readdirnames(1)
+				dir.Close()
+				if err == io.EOF {
+					err = nil
+				}
+			}
+		}
+		if err != nil {
+			problems++
+			result.Details = append(result.Details, fmt.Sprintf("%s (ID: %s): %v", entry.Alias, id, err))
+			if path != "" && os.IsNotExist(err) {
+				result.Fixes = append(result.Fixes, repointFixes(ctx, id, path)...)
+			}
+		}
+	}
+
+	result.Message = fmt.Sprintf("%d of %d tracked paths readable", len(ctx.Data.TrackedDirs)-problems, len(ctx.Data.TrackedDirs))
+	if problems > 0 {
+		result.Status = StatusFail
+		result.Hint = "Restore the missing directories, fix their permissions, or remove the entries with mmsync remove."
+	}
+	return result
+}
+
+// Levels below the nearest existing ancestor searched for a moved directory.
+const movedSearchDepth = 3
+
+// Offers to re-point entry id at a directory of the same name found below the
+// nearest existing ancestor of its missing path. Nothing is offered when the
+// search finds no candidate or more than one; mmsync relocate searches wider.
+func repointFixes(ctx *Context, id string, missingPath string) []Fix {
+	root := filepath.Dir(missingPath)
+	for {
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			break
+		}
+		root = filepath.Dir(root)
+	}
+	if root == filepath.Dir(root) {
+		return nil
+	}
+
+	candidates := ctx.Config.FindRelocated(ctx.Data, missingPath, []string{root}, movedSearchDepth)
+	if len(candidates) != 1 {
+		return nil
+	}
+
+	newPath := candidates[0]
+	return []Fix{{
+		Description: fmt.Sprintf("Re-point '%s' (ID: %s) from %s to %s", ctx.Data.TrackedDirs[id].Alias, id, missingPath, newPath),
+		Apply: func() error {
+			entry := ctx.Data.TrackedDirs[id]
+			entry.TargetPath = config.PortablePath(newPath)
+			ctx.Data.TrackedDirs[id] = entry
+			return saveData(ctx)
+		},
+	}}
+}
+
+// Saves the database and the repository manifests after a fix changed tracking.
+func saveData(ctx *Context) error {
+	if err := ctx.Data.SaveData(ctx.Config.ConfigSchema.DbPath); err != nil {
+		return err
+	}
+	return ctx.Config.WriteManifests(ctx.Data)
+}
+
+func checkStale(ctx *Context) Result {
+	staleDays := ctx.Config.ConfigSchema.Health.StaleDays
+	if staleDays == 0 {
+		return Result{Status: StatusSkip, Message: "disabled by health.stale_days"}
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return Result{Status: StatusSkip, Message: "git is not installed"}
+	}
+
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -staleDays)
+	result := Result{Status: StatusPass}
+	stale := 0
+
+	for _, id := range ctx.Data.IDs() {
+		entry := ctx.Data.TrackedDirs[id]
+		if entry.IsPaused(now) {
+			continue
+		}
+		repo, err := ctx.Config.RepositoryFor(entry)
+		if err != nil {
+			continue
+		}
+
+		out, err := git(context.Background(), repo.Path, "log", "-1", "--format=%ct", "--", entry.Alias)
+		last, parseErr := strconv.ParseInt(out, 10, 64)
+		switch {
+		case err != nil:
+			continue
+		case parseErr != nil:
+			stale++
+			result.Details = append(result.Details, fmt.Sprintf("%s (ID: %s): never committed", entry.Alias, id))
+		case time.Unix(last, 0).Before(cutoff):
+			stale++
+			result.Details = append(result.Details, fmt.Sprintf("%s (ID: %s): last committed %s", entry.Alias, id, time.Unix(last, 0).Format(time.DateOnly)))
+		}
+	}
+
+	result.Message = fmt.Sprintf("%d entries without a commit in %d days", stale, staleDays)
+	if stale > 0 {
+		result.Status = StatusWarn
+		result.Hint = "Sync the stale entries, or pause them if they are meant to be idle."
+	}
+	return result
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"github.com/bladeacer/mmsync/cmd"
-	"github.com/bladeacer/mmsync/config"
 	"os"
 )
 
@@ -16,18 +15,5 @@ func main() {
 		os.Exit(1)
 	}
 
-	appConfig, err := config.LoadConfig()
-	dataStore, err2 := config.LoadDataStore()
-
-	if err != nil {
-		fmt.Printf("Error loading configuration: %v\n", err)
-		os.Exit(1)
-	}
-
-	if err2 != nil {
-		fmt.Printf("Error loading database: %v\n", err2)
-		os.Exit(1)
-	}
-
-	cmd.Execute(appConfig, dataStore)
+	cmd.Execute()
 }
@@ -0,0 +1,102 @@
+package syncer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Rename is a file that moved without changing content.
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Changes are the file operations turning one scan into another, each list
+// sorted by path.
+type Changes struct {
+	Added    []string `json:"added,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+	Deleted  []string `json:"deleted,omitempty"`
+	Renamed  []Rename `json:"renamed,omitempty"`
+	// Paths whose metadata alone changed, see Metadata
+	Metadata []string `json:"metadata,omitempty"`
+}
+
+// Diff compares the files of the last sync with the current ones. A deleted
+// file and an added file with the same hash are reported as a rename.
+func Diff(old map[string]FileState, current map[string]FileState) Changes {
+	var c Changes
+	for _, path := range sortedPaths(current) {
+		prev, ok := old[path]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, path)
+		case prev.SHA256 != current[path].SHA256 || prev.Mode != current[path].Mode:
+			c.Modified = append(c.Modified, path)
+		}
+	}
+	for _, path := range sortedPaths(old) {
+		if _, ok := current[path]; !ok {
+			c.Deleted = append(c.Deleted, path)
+		}
+	}
+
+	// Pair deleted and added files by hash, each file at most once
+	deletedByHash := make(map[string][]string)
+	for _, path := range c.Deleted {
+		hash := old[path].SHA256
+		deletedByHash[hash] = append(deletedByHash[hash], path)
+	}
+	renamedFrom := make(map[string]bool)
+	var added []string
+	for _, path := range c.Added {
+		hash := current[path].SHA256
+		if candidates := deletedByHash[hash]; len(candidates) > 0 && old[candidates[0]].Mode == current[path].Mode {
+			c.Renamed = append(c.Renamed, Rename{From: candidates[0], To: path})
+			renamedFrom[candidates[0]] = true
+			deletedByHash[hash] = candidates[1:]
+			continue
+		}
+		added = append(added, path)
+	}
+	c.Added = added
+
+	var deleted []string
+	for _, path := range c.Deleted {
+		if !renamedFrom[path] {
+			deleted = append(deleted, path)
+		}
+	}
+	c.Deleted = deleted
+
+	return c
+}
+
+// Empty reports whether there is nothing to do.
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Modified) == 0 && len(c.Deleted) == 0 && len(c.Renamed) == 0 && len(c.Metadata) == 0
+}
+
+// Count returns the number of files to copy, delete or rename.
+func (c Changes) Count() int {
+	return len(c.Added) + len(c.Modified) + len(c.Deleted) + len(c.Renamed)
+}
+
+// Summary counts the changes, e.g. "2 added, 1 modified, 0 deleted, 1 renamed".
+// Metadata changes are only counted when there are some.
+func (c Changes) Summary() string {
+	summary := fmt.Sprintf("%d added, %d modified, %d deleted, %d renamed", len(c.Added), len(c.Modified), len(c.Deleted), len(c.Renamed))
+	if len(c.Metadata) > 0 {
+		summary += fmt.Sprintf(", %d metadata changed", len(c.Metadata))
+	}
+	return summary
+}
+
+func sortedPaths(files map[string]FileState) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
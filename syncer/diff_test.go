@@ -0,0 +1,96 @@
+package syncer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func file(hash string) FileState {
+	return FileState{Mode: 0644, SHA256: hash}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     map[string]FileState
+		current map[string]FileState
+		want    Changes
+	}{
+		{
+			name:    "nothing changed",
+			old:     map[string]FileState{"a": file("1")},
+			current: map[string]FileState{"a": file("1")},
+			want:    Changes{},
+		},
+		{
+			name:    "first sync",
+			old:     nil,
+			current: map[string]FileState{"b": file("2"), "a": file("1")},
+			want:    Changes{Added: []string{"a", "b"}},
+		},
+		{
+			name:    "added, modified and deleted",
+			old:     map[string]FileState{"a": file("1"), "b": file("2")},
+			current: map[string]FileState{"a": file("3"), "c": file("4")},
+			want:    Changes{Added: []string{"c"}, Modified: []string{"a"}, Deleted: []string{"b"}},
+		},
+		{
+			name:    "mode change is a modification",
+			old:     map[string]FileState{"run.sh": file("1")},
+			current: map[string]FileState{"run.sh": {Mode: 0755, SHA256: "1"}},
+			want:    Changes{Modified: []string{"run.sh"}},
+		},
+		{
+			name:    "rename",
+			old:     map[string]FileState{"old/a": file("1")},
+			current: map[string]FileState{"new/a": file("1")},
+			want:    Changes{Renamed: []Rename{{From: "old/a", To: "new/a"}}},
+		},
+		{
+			name:    "rename with another mode is a delete and an add",
+			old:     map[string]FileState{"a": file("1")},
+			current: map[string]FileState{"b": {Mode: 0755, SHA256: "1"}},
+			want:    Changes{Added: []string{"b"}, Deleted: []string{"a"}},
+		},
+		{
+			name:    "duplicates are paired once each",
+			old:     map[string]FileState{"a1": file("1"), "a2": file("1")},
+			current: map[string]FileState{"b1": file("1"), "b2": file("1"), "b3": file("1")},
+			want: Changes{
+				Added:   []string{"b3"},
+				Renamed: []Rename{{From: "a1", To: "b1"}, {From: "a2", To: "b2"}},
+			},
+		},
+		{
+			name:    "everything deleted",
+			old:     map[string]FileState{"a": file("1"), "b": file("2")},
+			current: map[string]FileState{},
+			want:    Changes{Deleted: []string{"a", "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.old, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangesSummary(t *testing.T) {
+	tests := []struct {
+		changes Changes
+		want    string
+	}{
+		{Changes{}, "0 added, 0 modified, 0 deleted, 0 renamed"},
+		{Changes{Added: []string{"a"}, Renamed: []Rename{{From: "b", To: "c"}}}, "1 added, 0 modified, 0 deleted, 1 renamed"},
+		{Changes{Metadata: []string{"."}}, "0 added, 0 modified, 0 deleted, 0 renamed, 1 metadata changed"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.changes.Summary(); got != tt.want {
+			t.Errorf("Summary() = %q, want %q", got, tt.want)
+		}
+	}
+}
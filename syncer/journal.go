@@ -0,0 +1,70 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// JournalEntry records the sync of one tracked directory. The journal holds
+// one JSON entry per line.
+type JournalEntry struct {
+	Time     time.Time `json:"time"`
+	ID       string    `json:"id"`
+	Alias    string    `json:"alias"`
+	Repo     string    `json:"repo"`
+	Added    int       `json:"added"`
+	Modified int       `json:"modified"`
+	Deleted  int       `json:"deleted"`
+	Renamed  int       `json:"renamed"`
+	Metadata int       `json:"metadata,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// JournalPath returns the journal in stateDir.
+func JournalPath(stateDir string) string {
+	return filepath.Join(stateDir, "journal.jsonl")
+}
+
+// NewJournalEntry summarizes a result for the journal.
+func NewJournalEntry(r Result, at time.Time) JournalEntry {
+	entry := JournalEntry{
+		Time:     at.UTC(),
+		ID:       r.Job.ID,
+		Alias:    r.Job.Entry.Alias,
+		Repo:     r.Job.Repo.Name,
+		Added:    len(r.Changes.Added),
+		Modified: len(r.Changes.Modified),
+		Deleted:  len(r.Changes.Deleted),
+		Renamed:  len(r.Changes.Renamed),
+		Metadata: len(r.Changes.Metadata),
+		Commit:   r.Commit,
+	}
+	if r.Err != nil {
+		entry.Error = r.Err.Error()
+	}
+	return entry
+}
+
+// AppendJournal appends entries to the journal at path.
+func AppendJournal(path string, entries ...JournalEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write journal %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package syncer copies tracked directories into their repositories and
+// commits the result.
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestVersion is the version of the file manifest format.
+const ManifestVersion = 1
+
+// FileState is what a sync recorded about one file of a tracked directory.
+type FileState struct {
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	Mode    fs.FileMode `json:"mode"`
+	SHA256  string      `json:"sha256"`
+	// Target of a symbolic link. SHA256 is then the hash of the target.
+	Link string `json:"link,omitempty"`
+}
+
+// Unchanged reports whether a file can be assumed to have the same content
+// as when prev was recorded without reading it again.
+func (s FileState) Unchanged(prev FileState) bool {
+	return s.Size == prev.Size && s.ModTime.Equal(prev.ModTime) && s.Mode == prev.Mode && s.Link == prev.Link
+}
+
+// Manifest records the files of a tracked directory as of its last sync,
+// keyed by slash-separated path relative to the directory. It lives in the
+// state directory, next to the database, and is never committed.
+type Manifest struct {
+	ManifestVersion int       `json:"manifest_version"`
+	Alias           string    `json:"alias"`
+	Repo            string    `json:"repo"`
+	SyncedAt        time.Time `json:"synced_at"`
+	// Hash of the tree committed for the alias folder by that sync. The
+	// manifest only describes the folder while HEAD still has this tree.
+	Tree  string               `json:"tree,omitempty"`
+	Files map[string]FileState `json:"files"`
+}
+
+// ManifestPath returns the manifest of tracked directory id in stateDir.
+func ManifestPath(stateDir string, id string) string {
+	return filepath.Join(stateDir, "manifests", id+".json")
+}
+
+// LoadManifest reads the manifest at path. A missing manifest is not an
+// error; nil is returned instead.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if m.ManifestVersion != ManifestVersion {
+		return nil, fmt.Errorf("manifest %s has unsupported version %d", path, m.ManifestVersion)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]FileState)
+	}
+	return &m, nil
+}
+
+// Save atomically writes the manifest to path.
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// Writes data next to path and renames it into place, so that an interrupted
+// write never leaves a partial file behind.
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,371 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MetadataVersion is the version of the metadata sidecar format.
+const MetadataVersion = 1
+
+// Metadata keeps what git drops about a tracked directory: modes, owners,
+// mtimes, extended attributes and directories, empty ones included. It is
+// committed next to the alias folder, see config.AliasMetadataFile.
+type Metadata struct {
+	MetadataVersion int    `json:"metadata_version"`
+	Alias           string `json:"alias"`
+	// Keyed by slash-separated path relative to the tracked directory, "."
+	// being the directory itself
+	Entries map[string]EntryMetadata `json:"entries"`
+}
+
+// EntryMetadata is the metadata of one file, symbolic link or directory. The
+// mode is written as ls shows it, e.g. "drwx------", so that the sidecar
+// reads well in a diff.
+type EntryMetadata struct {
+	Mode fs.FileMode `json:"mode"`
+	// User and group names, or their numeric IDs when they have no name
+	Owner string `json:"owner,omitempty"`
+	Group string `json:"group,omitempty"`
+	// Not recorded for directories, whose mtime changes with every file
+	// added to or removed from them
+	ModTime time.Time         `json:"mtime,omitzero"`
+	Link    string            `json:"link,omitempty"`
+	Xattrs  map[string][]byte `json:"xattrs,omitempty"`
+}
+
+// Equal reports whether two entries record the same metadata.
+func (e EntryMetadata) Equal(other EntryMetadata) bool {
+	return e.Mode == other.Mode && e.Owner == other.Owner && e.Group == other.Group &&
+		e.ModTime.Equal(other.ModTime) && e.Link == other.Link &&
+		maps.EqualFunc(e.Xattrs, other.Xattrs, bytes.Equal)
+}
+
+func (e EntryMetadata) MarshalJSON() ([]byte, error) {
+	type plain EntryMetadata
+	return json.Marshal(struct {
+		Mode string `json:"mode"`
+		plain
+	}{e.Mode.String(), plain(e)})
+}
+
+func (e *EntryMetadata) UnmarshalJSON(data []byte) error {
+	type plain EntryMetadata
+	aux := struct {
+		Mode string `json:"mode"`
+		*plain
+	}{plain: (*plain)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	mode, err := parseMode(aux.Mode)
+	if err != nil {
+		return err
+	}
+	e.Mode = mode
+	return nil
+}
+
+// Parses a mode written by fs.FileMode.String.
+func parseMode(s string) (fs.FileMode, error) {
+	const typeLetters, rwx = "dalTLDpSugct?", "rwxrwxrwx"
+	if len(s) < len(rwx)+1 {
+		return 0, fmt.Errorf("invalid mode '%s'", s)
+	}
+
+	var mode fs.FileMode
+	if prefix := s[:len(s)-len(rwx)]; prefix != "-" {
+		for _, c := range prefix {
+			i := strings.IndexRune(typeLetters, c)
+			if i < 0 {
+				return 0, fmt.Errorf("invalid mode '%s'", s)
+			}
+			mode |= 1 << (31 - i)
+		}
+	}
+	for i, c := range s[len(s)-len(rwx):] {
+		switch c {
+		case '-':
+		case rune(rwx[i]):
+			mode |= 1 << (8 - i)
+		default:
+			return 0, fmt.Errorf("invalid mode '%s'", s)
+		}
+	}
+	return mode, nil
+}
+
+// ReadMetadata records the metadata of dir, its directories and the files
+// in files, as returned by Scan. Extended attributes are only read with
+// xattrs.
+func ReadMetadata(ctx context.Context, dir string, files map[string]FileState, xattrs bool) (*Metadata, error) {
+	m := &Metadata{MetadataVersion: MetadataVersion, Entries: make(map[string]EntryMetadata)}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" && path != dir {
+			return filepath.SkipDir
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		state, isFile := files[rel]
+		if !d.IsDir() && !isFile {
+			return nil
+		}
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		entry := EntryMetadata{Mode: info.Mode()}
+		entry.Owner, entry.Group = fileOwner(info)
+		if isFile {
+			entry.ModTime, entry.Link = state.ModTime, state.Link
+		}
+		if xattrs && info.Mode()&fs.ModeSymlink == 0 {
+			if entry.Xattrs, err = readXattrs(path); err != nil {
+				return err
+			}
+		}
+		m.Entries[rel] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s: %w", dir, err)
+	}
+	return m, nil
+}
+
+// LoadMetadata reads the sidecar at path. A missing sidecar is not an error;
+// nil is returned instead.
+func LoadMetadata(path string) (*Metadata, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata %s: %w", path, err)
+	}
+
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata %s: %w", path, err)
+	}
+	if m.MetadataVersion != MetadataVersion {
+		return nil, fmt.Errorf("metadata %s has unsupported version %d", path, m.MetadataVersion)
+	}
+	if m.Entries == nil {
+		m.Entries = make(map[string]EntryMetadata)
+	}
+	for name := range m.Entries {
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("metadata %s names '%s', which is outside the tracked directory", path, name)
+		}
+	}
+	return &m, nil
+}
+
+// Save atomically writes the sidecar to path.
+func (m *Metadata) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write metadata %s: %w", path, err)
+	}
+	return nil
+}
+
+// Returns the sorted paths whose metadata differs between old and current,
+// leaving out those already added, modified, deleted or renamed by c.
+func metadataChanges(old *Metadata, current *Metadata, c Changes) []string {
+	covered := make(map[string]bool)
+	for _, paths := range [][]string{c.Added, c.Modified, c.Deleted} {
+		for _, path := range paths {
+			covered[path] = true
+		}
+	}
+	for _, r := range c.Renamed {
+		covered[r.From], covered[r.To] = true, true
+	}
+
+	var oldEntries map[string]EntryMetadata
+	if old != nil {
+		oldEntries = old.Entries
+	}
+	changed := make(map[string]bool)
+	for path, entry := range current.Entries {
+		if prev, ok := oldEntries[path]; !ok || !prev.Equal(entry) {
+			changed[path] = true
+		}
+	}
+	for path := range oldEntries {
+		if _, ok := current.Entries[path]; !ok {
+			changed[path] = true
+		}
+	}
+
+	var paths []string
+	for path := range changed {
+		if !covered[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// MetadataReport counts what RestoreMetadata could not apply. Owners can
+// usually only be given away by root.
+type MetadataReport struct {
+	Entries       int
+	OwnersSkipped int
+	XattrsSkipped int
+	// Entries that would reach outside the directory, or whose file has
+	// another type than recorded
+	Skipped int
+}
+
+// RestoreMetadata applies m to the restored copy of a tracked directory at
+// dir. Missing directories and symbolic links are created; other missing
+// files are left out. Directories are done last, deepest first, so that a
+// read-only one does not get in the way.
+//
+// The sidecar comes from a repository that may be shared, so nothing outside
+// dir is touched, through .. or a symbolic link, and setuid and setgid bits
+// are dropped unless setuid is set.
+func RestoreMetadata(dir string, m *Metadata, setuid bool) (MetadataReport, error) {
+	var report MetadataReport
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return report, err
+	}
+
+	paths := make([]string, 0, len(m.Entries))
+	for path := range m.Entries {
+		if !filepath.IsLocal(filepath.FromSlash(path)) {
+			report.Skipped++
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		di, dj := m.Entries[paths[i]].Mode.IsDir(), m.Entries[paths[j]].Mode.IsDir()
+		if di != dj {
+			return dj
+		}
+		if di {
+			return strings.Count(paths[i], "/") > strings.Count(paths[j], "/") ||
+				strings.Count(paths[i], "/") == strings.Count(paths[j], "/") && paths[i] > paths[j]
+		}
+		return paths[i] < paths[j]
+	})
+
+	for _, path := range paths {
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if m.Entries[path].Mode.IsDir() && insideDir(root, path, target) {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	modeBits := fs.ModePerm | fs.ModeSticky
+	if setuid {
+		modeBits |= fs.ModeSetuid | fs.ModeSetgid
+	}
+
+	for _, path := range paths {
+		entry := m.Entries[path]
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if !insideDir(root, path, target) {
+			report.Skipped++
+			continue
+		}
+
+		info, err := os.Lstat(target)
+		if os.IsNotExist(err) && entry.Mode&fs.ModeSymlink != 0 {
+			if err := os.Symlink(entry.Link, target); err != nil {
+				return report, err
+			}
+		} else if err != nil {
+			continue
+		} else if info.Mode().Type() != entry.Mode.Type() {
+			report.Skipped++
+			continue
+		}
+
+		if err := lchown(target, entry.Owner, entry.Group); err != nil {
+			report.OwnersSkipped++
+		}
+		if entry.Mode&fs.ModeSymlink != 0 {
+			report.Entries++
+			continue
+		}
+
+		for name, value := range entry.Xattrs {
+			if err := setXattr(target, name, value); err != nil {
+				report.XattrsSkipped++
+			}
+		}
+		if err := os.Chmod(target, entry.Mode&modeBits); err != nil {
+			return report, fmt.Errorf("failed to set the mode of %s: %w", target, err)
+		}
+		if !entry.ModTime.IsZero() {
+			if err := os.Chtimes(target, entry.ModTime, entry.ModTime); err != nil {
+				return report, fmt.Errorf("failed to set the mtime of %s: %w", target, err)
+			}
+		}
+		report.Entries++
+	}
+	return report, nil
+}
+
+// Reports whether target, the entry at path, lies inside root, a directory
+// with its symbolic links resolved, once the symbolic links of its nearest
+// existing parent are resolved.
+func insideDir(root string, path string, target string) bool {
+	if path == "." {
+		return true
+	}
+	parent := filepath.Dir(target)
+	for {
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		next := filepath.Dir(parent)
+		if next == parent {
+			return false
+		}
+		parent = next
+	}
+
+	resolved, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return false
+	}
+	return resolved == root || strings.HasPrefix(resolved, root+string(filepath.Separator))
+}
+
+// errUnsupported is returned where a platform cannot read or set some metadata.
+var errUnsupported = errors.New("not supported on this platform")
@@ -0,0 +1,251 @@
+package syncer
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    fs.FileMode
+		wantErr bool
+	}{
+		{in: "-rw-r--r--", want: 0644},
+		{in: "-rwx------", want: 0700},
+		{in: "----------", want: 0},
+		{in: "drwxr-xr-x", want: fs.ModeDir | 0755},
+		{in: "Lrwxrwxrwx", want: fs.ModeSymlink | 0777},
+		{in: "dtrwxrwxrwx", want: fs.ModeDir | fs.ModeSticky | 0777},
+		{in: "urwxr-xr-x", want: fs.ModeSetuid | 0755},
+		{in: "grwxr-xr-x", want: fs.ModeSetgid | 0755},
+		{in: "prw-------", want: fs.ModeNamedPipe | 0600},
+		{in: "", wantErr: true},
+		{in: "rw-r--r--", wantErr: true},
+		{in: "-rw-r--r-x-", wantErr: true},
+		{in: "zrw-r--r--", wantErr: true},
+		{in: "-rw-r--r-?", wantErr: true},
+		{in: "-wr-r--r--", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseMode(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMode(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseMode(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseModeRoundTrip(t *testing.T) {
+	modes := []fs.FileMode{
+		0, 0644, 0755, 0777,
+		fs.ModeDir | 0700,
+		fs.ModeSymlink | 0777,
+		fs.ModeDir | fs.ModeSticky | 0777,
+		fs.ModeSetuid | fs.ModeSetgid | 0755,
+		fs.ModeNamedPipe | 0600,
+		fs.ModeSocket | 0755,
+		fs.ModeDevice | fs.ModeCharDevice | 0666,
+	}
+
+	for _, mode := range modes {
+		got, err := parseMode(mode.String())
+		if err != nil {
+			t.Errorf("parseMode(%q) error = %v", mode.String(), err)
+			continue
+		}
+		if got != mode {
+			t.Errorf("parseMode(%q) = %v, want %v", mode.String(), got, mode)
+		}
+	}
+}
+
+func TestEntryMetadataJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry EntryMetadata
+	}{
+		{"file", EntryMetadata{
+			Mode:    0600,
+			Owner:   "alice",
+			Group:   "staff",
+			ModTime: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		}},
+		{"directory", EntryMetadata{Mode: fs.ModeDir | 0700, Owner: "1000", Group: "1000"}},
+		{"symbolic link", EntryMetadata{Mode: fs.ModeSymlink | 0777, Link: "cfg/config"}},
+		{"xattrs", EntryMetadata{Mode: 0644, Xattrs: map[string][]byte{"user.tag": []byte("red")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.entry)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var fields map[string]any
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			if fields["mode"] != tt.entry.Mode.String() {
+				t.Errorf("mode written as %v, want %q", fields["mode"], tt.entry.Mode.String())
+			}
+
+			var got EntryMetadata
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal(%s) error = %v", data, err)
+			}
+			if !got.Equal(tt.entry) {
+				t.Errorf("round trip of %s = %+v, want %+v", data, got, tt.entry)
+			}
+		})
+	}
+
+	var entry EntryMetadata
+	if err := json.Unmarshal([]byte(`{"mode":"-rw-r--r-z"}`), &entry); err == nil {
+		t.Error("Unmarshal() accepted an invalid mode")
+	}
+}
+
+func TestMetadataChanges(t *testing.T) {
+	file := EntryMetadata{Mode: 0644}
+	old := &Metadata{Entries: map[string]EntryMetadata{
+		".":     {Mode: fs.ModeDir | 0755},
+		"a":     file,
+		"b":     file,
+		"gone":  file,
+		"moved": file,
+	}}
+	current := &Metadata{Entries: map[string]EntryMetadata{
+		".":        {Mode: fs.ModeDir | 0700},
+		"a":        file,
+		"b":        {Mode: 0600},
+		"new":      file,
+		"new-dir":  {Mode: fs.ModeDir | 0755},
+		"moved-to": file,
+	}}
+
+	tests := []struct {
+		name    string
+		old     *Metadata
+		changes Changes
+		want    []string
+	}{
+		{"nothing covered", old, Changes{}, []string{".", "b", "gone", "moved", "moved-to", "new", "new-dir"}},
+		{"covered by file changes", old, Changes{
+			Added:   []string{"new"},
+			Deleted: []string{"gone"},
+			Renamed: []Rename{{From: "moved", To: "moved-to"}},
+		}, []string{".", "b", "new-dir"}},
+		{"no sidecar yet", nil, Changes{}, []string{".", "a", "b", "moved-to", "new", "new-dir"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metadataChanges(tt.old, current, tt.changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metadataChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMetadataRejectsOutsidePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"inside", "sub/file", false},
+		{"directory itself", ".", false},
+		{"parent", "../file", true},
+		{"nested parent", "sub/../../file", true},
+		{"absolute", "/etc/passwd", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(&Metadata{
+				MetadataVersion: MetadataVersion,
+				Entries:         map[string]EntryMetadata{tt.path: {Mode: 0644}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "notes.json")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := LoadMetadata(path); (err != nil) != tt.wantErr {
+				t.Errorf("LoadMetadata() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRestoreMetadataStaysInside(t *testing.T) {
+	base := t.TempDir()
+	dir, outside := filepath.Join(base, "notes"), filepath.Join(base, "outside")
+	for _, d := range []string{dir, outside} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tool"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Metadata{Entries: map[string]EntryMetadata{
+		"../outside/secret": {Mode: 0666},
+		"escape/secret":     {Mode: 0666},
+		"escape":            {Mode: fs.ModeDir | 0777},
+		"tool":              {Mode: fs.ModeSetuid | 0755},
+	}}
+	report, err := RestoreMetadata(dir, m, false)
+	if err != nil {
+		t.Fatalf("RestoreMetadata() error = %v", err)
+	}
+	if report.Skipped != 3 {
+		t.Errorf("skipped %d entries, want 3", report.Skipped)
+	}
+
+	info, err := os.Stat(filepath.Join(outside, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode outside the directory changed to %v", info.Mode())
+	}
+	info, err = os.Stat(filepath.Join(dir, "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSetuid != 0 {
+		t.Errorf("setuid bit restored without being asked: %v", info.Mode())
+	}
+
+	if _, err := RestoreMetadata(dir, m, true); err != nil {
+		t.Fatalf("RestoreMetadata() error = %v", err)
+	}
+	if info, err = os.Stat(filepath.Join(dir, "tool")); err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSetuid == 0 {
+		t.Errorf("setuid bit not restored when asked: %v", info.Mode())
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package syncer
+
+import "io/fs"
+
+func fileOwner(info fs.FileInfo) (string, string) {
+	return "", ""
+}
+
+func lchown(path string, owner string, group string) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+//go:build linux || darwin
+
+package syncer
+
+import (
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// Name lookups are cached, since every file of a directory usually has the
+// same owner.
+var (
+	userNames  sync.Map
+	groupNames sync.Map
+)
+
+// Returns the names of the user and group owning a file, or their numeric
+// IDs when they have no name.
+func fileOwner(info fs.FileInfo) (string, string) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+	uid, gid := strconv.FormatUint(uint64(stat.Uid), 10), strconv.FormatUint(uint64(stat.Gid), 10)
+
+	owner, ok := userNames.Load(uid)
+	if !ok {
+		owner = uid
+		if u, err := user.LookupId(uid); err == nil {
+			owner = u.Username
+		}
+		userNames.Store(uid, owner)
+	}
+	group, ok := groupNames.Load(gid)
+	if !ok {
+		group = gid
+		if g, err := user.LookupGroupId(gid); err == nil {
+			group = g.Name
+		}
+		groupNames.Store(gid, group)
+	}
+	return owner.(string), group.(string)
+}
+
+// Gives path, without following a symbolic link, the named owner and group
+// unless it already has them.
+func lchown(path string, owner string, group string) error {
+	if owner == "" && group == "" {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return errUnsupported
+	}
+
+	uid, gid := int(stat.Uid), int(stat.Gid)
+	if owner != "" {
+		if uid, err = lookupID(owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		}); err != nil {
+			return err
+		}
+	}
+	if group != "" {
+		if gid, err = lookupID(group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if uid == int(stat.Uid) && gid == int(stat.Gid) {
+		return nil
+	}
+	return os.Lchown(path, uid, gid)
+}
+
+// Resolves a user or group name to its ID. A name unknown on this machine
+// that is a number is taken as the ID.
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	id, err := lookup(name)
+	if err != nil {
+		if n, convErr := strconv.Atoi(name); convErr == nil {
+			return n, nil
+		}
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
@@ -0,0 +1,229 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bladeacer/mmsync/config"
+)
+
+// PlanVersion is the version of the plan file format.
+const PlanVersion = 2
+
+// Plan is a reviewed sync, saved by `mmsync sync --plan` and carried out by
+// `mmsync apply`.
+type Plan struct {
+	PlanVersion int          `json:"plan_version"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Profile     string       `json:"profile"`
+	Rehash      bool         `json:"rehash"`
+	Xattrs      bool         `json:"xattrs"`
+	Aliases     []*AliasPlan `json:"aliases"`
+}
+
+// AliasPlan is what syncing one tracked directory will do, with what the
+// source and the repository looked like when it was made.
+type AliasPlan struct {
+	ID     string `json:"id"`
+	Alias  string `json:"alias"`
+	Repo   string `json:"repo"`
+	Source string `json:"source"`
+	// Commit checked out in the repository, empty before the first commit
+	RepoHead string `json:"repo_head"`
+	// SHA-256 of the git status of the alias folder, empty when it has no
+	// uncommitted changes. Those are discarded when the plan is carried out.
+	RepoStatus   string               `json:"repo_status,omitempty"`
+	SourceDigest string               `json:"source_digest"`
+	Changes      Changes              `json:"changes"`
+	Files        map[string]FileState `json:"files"`
+	// Sidecar committed with the changes
+	Metadata *Metadata `json:"metadata"`
+}
+
+// PlanJob works out what syncing a tracked directory would do without
+// changing anything.
+//
+// The changes are relative to the last commit of the alias folder, as
+// recorded in the manifest. Files whose size, mtime and mode match the
+// manifest are not read again. The manifest is not trusted, and the folder is
+// scanned instead, when it is missing, belongs to another alias or
+// repository, when commits made elsewhere, e.g. pulled in, changed the folder
+// since, or with Rehash. Scanning needs a folder without uncommitted changes.
+func PlanJob(ctx context.Context, job Job, opts Options) (*AliasPlan, error) {
+	source, err := job.Entry.Path()
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(source); err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", source, err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", source)
+	}
+
+	plan := &AliasPlan{ID: job.ID, Alias: job.Entry.Alias, Repo: job.Repo.Name, Source: source}
+	if plan.RepoHead, plan.RepoStatus, err = repoState(ctx, job.Repo.Path, job.Entry.Alias); err != nil {
+		return nil, err
+	}
+
+	mirror := config.AliasDir(job.Repo.Path, job.Entry.Alias)
+	manifest, err := LoadManifest(ManifestPath(opts.StateDir, job.ID))
+	_, mirrorErr := os.Stat(mirror)
+	trusted := err == nil && manifest != nil && !opts.Rehash && mirrorErr == nil &&
+		manifest.Alias == job.Entry.Alias && manifest.Repo == job.Repo.Name
+	if trusted {
+		tree, err := aliasTree(ctx, job.Repo.Path, job.Entry.Alias)
+		if err != nil {
+			return nil, err
+		}
+		trusted = tree != "" && tree == manifest.Tree
+	}
+
+	onFile := func(n int) { opts.report(job, StageScanning, n, 0) }
+	opts.report(job, StageScanning, 0, 0)
+
+	var old, previous map[string]FileState
+	switch {
+	case trusted:
+		old, previous = manifest.Files, manifest.Files
+	case plan.RepoStatus != "":
+		return nil, fmt.Errorf("%s has uncommitted changes and no manifest to compare with; commit or discard them first", mirror)
+	default:
+		if old, err = Scan(ctx, mirror, nil, onFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if plan.Files, err = Scan(ctx, source, previous, onFile); err != nil {
+		return nil, err
+	}
+	plan.SourceDigest = digestFiles(plan.Files)
+	plan.Changes = Diff(old, plan.Files)
+
+	if plan.Metadata, err = ReadMetadata(ctx, source, plan.Files, opts.Xattrs); err != nil {
+		return nil, err
+	}
+	plan.Metadata.Alias = job.Entry.Alias
+	// An unreadable sidecar is replaced like a missing one
+	committed, _ := LoadMetadata(filepath.Join(job.Repo.Path, config.AliasMetadataFile(job.Entry.Alias)))
+	plan.Changes.Metadata = metadataChanges(committed, plan.Metadata, plan.Changes)
+	return plan, nil
+}
+
+// Verify checks that neither the source nor the repository changed since the
+// plan was made for job with opts.
+func (p *AliasPlan) Verify(ctx context.Context, job Job, opts Options) error {
+	source, err := job.Entry.Path()
+	if err != nil {
+		return err
+	}
+	if job.Entry.Alias != p.Alias || job.Repo.Name != p.Repo || source != p.Source {
+		return fmt.Errorf("'%s' (ID: %s) was re-pointed, renamed or moved to another repository", p.Alias, p.ID)
+	}
+
+	head, status, err := repoState(ctx, job.Repo.Path, job.Entry.Alias)
+	if err != nil {
+		return err
+	}
+	if head != p.RepoHead {
+		return fmt.Errorf("repository %s has new commits", job.Repo.Path)
+	}
+	if status != p.RepoStatus {
+		return fmt.Errorf("uncommitted changes in %s changed", config.AliasDir(job.Repo.Path, job.Entry.Alias))
+	}
+
+	previous := p.Files
+	if opts.Rehash {
+		previous = nil
+	}
+	files, err := Scan(ctx, source, previous, nil)
+	if err != nil {
+		return err
+	}
+	if digestFiles(files) != p.SourceDigest {
+		return fmt.Errorf("files in %s changed", source)
+	}
+
+	metadata, err := ReadMetadata(ctx, source, files, opts.Xattrs)
+	if err != nil {
+		return err
+	}
+	if len(metadataChanges(p.Metadata, metadata, Changes{})) > 0 {
+		return fmt.Errorf("permissions, owners or directories in %s changed", source)
+	}
+	return nil
+}
+
+// Returns the commit checked out in the repository and a digest of the git
+// status of the alias folder and its sidecar, empty when they are clean.
+func repoState(ctx context.Context, repoPath string, alias string) (string, string, error) {
+	if _, err := os.Stat(repoPath); err != nil {
+		return "", "", fmt.Errorf("cannot read repository %s: %w", repoPath, err)
+	}
+
+	head, err := git(ctx, repoPath, "rev-parse", "-q", "--verify", "HEAD")
+	if err != nil {
+		head = ""
+	}
+	status, err := git(ctx, repoPath, append([]string{"--no-optional-locks", "status", "--porcelain", "--untracked-files=all", "--"}, mirrorPaths(alias)...)...)
+	if err != nil {
+		return "", "", err
+	}
+	if status == "" {
+		return head, "", nil
+	}
+	sum := sha256.Sum256([]byte(status))
+	return head, hex.EncodeToString(sum[:]), nil
+}
+
+// Digests the recorded state of every file, in path order.
+func digestFiles(files map[string]FileState) string {
+	h := sha256.New()
+	for _, path := range sortedPaths(files) {
+		s := files[path]
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00%o\x00%s\x00%s\n", path, s.Size, s.ModTime.UnixNano(), uint32(s.Mode), s.SHA256, s.Link)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Save writes the plan to path.
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadPlan reads the plan at path.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if p.PlanVersion != PlanVersion {
+		return nil, fmt.Errorf("plan %s has unsupported version %d", filepath.Base(path), p.PlanVersion)
+	}
+	for _, a := range p.Aliases {
+		if a.Files == nil {
+			a.Files = make(map[string]FileState)
+		}
+		if a.Metadata == nil {
+			return nil, fmt.Errorf("plan %s has no metadata for '%s'", filepath.Base(path), a.Alias)
+		}
+	}
+	return &p, nil
+}
@@ -0,0 +1,129 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bladeacer/mmsync/config"
+)
+
+func TestDigestFiles(t *testing.T) {
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	base := map[string]FileState{
+		"a":   {Size: 1, ModTime: mtime, Mode: 0644, SHA256: "1"},
+		"b/c": {Size: 2, ModTime: mtime, Mode: 0600, SHA256: "2"},
+	}
+	changed := func(path string, edit func(*FileState)) map[string]FileState {
+		files := make(map[string]FileState)
+		for p, s := range base {
+			files[p] = s
+		}
+		s := files[path]
+		edit(&s)
+		files[path] = s
+		return files
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]FileState
+	}{
+		{"size", changed("a", func(s *FileState) { s.Size = 3 })},
+		{"mtime", changed("a", func(s *FileState) { s.ModTime = mtime.Add(time.Nanosecond) })},
+		{"mode", changed("b/c", func(s *FileState) { s.Mode = 0644 })},
+		{"hash", changed("b/c", func(s *FileState) { s.SHA256 = "3" })},
+		{"link", changed("a", func(s *FileState) { s.Link = "b/c" })},
+		{"renamed", map[string]FileState{"a": base["a"], "b/d": base["b/c"]}},
+		{"removed", map[string]FileState{"a": base["a"]}},
+		{"empty", map[string]FileState{}},
+	}
+
+	want := digestFiles(base)
+	if got := digestFiles(changed("a", func(*FileState) {})); got != want {
+		t.Fatalf("digest of an equal copy = %s, want %s", got, want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := digestFiles(tt.files); got == want {
+				t.Errorf("digest did not change")
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tests := []struct {
+		name    string
+		change  func(t *testing.T, source string, repo string)
+		wantErr bool
+	}{
+		{"unchanged", func(*testing.T, string, string) {}, false},
+		{"file edited", func(t *testing.T, source string, _ string) {
+			writeFile(t, filepath.Join(source, "a.txt"), "changed")
+		}, true},
+		{"file added", func(t *testing.T, source string, _ string) {
+			writeFile(t, filepath.Join(source, "new.txt"), "new")
+		}, true},
+		{"mode changed", func(t *testing.T, source string, _ string) {
+			if err := os.Chmod(filepath.Join(source, "a.txt"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}, true},
+		{"directory added", func(t *testing.T, source string, _ string) {
+			if err := os.Mkdir(filepath.Join(source, "empty"), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}, true},
+		{"uncommitted change in the repository", func(t *testing.T, _ string, repo string) {
+			writeFile(t, filepath.Join(repo, "notes", "stray.txt"), "stray")
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, repo := t.TempDir(), t.TempDir()
+			if output, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+				t.Fatalf("git init: %v: %s", err, output)
+			}
+			writeFile(t, filepath.Join(source, "a.txt"), "a")
+			writeFile(t, filepath.Join(source, "sub", "b.txt"), "b")
+
+			job := Job{
+				ID:    "1",
+				Entry: config.DirData{TargetPath: source, Alias: "notes"},
+				Repo:  config.Repository{Name: config.DefaultRepoName, Path: repo},
+			}
+			opts := Options{StateDir: t.TempDir()}
+			plan, err := PlanJob(context.Background(), job, opts)
+			if err != nil {
+				t.Fatalf("PlanJob() error = %v", err)
+			}
+			if len(plan.Changes.Added) != 2 {
+				t.Fatalf("PlanJob() added %v, want 2 files", plan.Changes.Added)
+			}
+
+			tt.change(t, source, repo)
+			if err := plan.Verify(context.Background(), job, opts); (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package syncer
+
+import (
+	"context"
+	"sync"
+)
+
+// RunAll syncs jobs with at most workers running at once and returns their
+// results in job order. onDone, when set, is called with each result as it
+// finishes, never concurrently. Jobs not started before ctx is cancelled
+// fail with its error.
+func RunAll(ctx context.Context, jobs []Job, opts Options, workers int, onDone func(Result)) []Result {
+	return runEach(ctx, jobs, workers, onDone, func(i int) Result {
+		return Run(ctx, jobs[i], opts)
+	})
+}
+
+// ExecuteAll carries out plans[i] for jobs[i] like RunAll.
+func ExecuteAll(ctx context.Context, jobs []Job, plans []*AliasPlan, opts Options, workers int, onDone func(Result)) []Result {
+	return runEach(ctx, jobs, workers, onDone, func(i int) Result {
+		return Execute(ctx, jobs[i], plans[i], opts)
+	})
+}
+
+// PlanAll plans jobs with at most workers running at once, returning the
+// plans and errors in job order.
+func PlanAll(ctx context.Context, jobs []Job, opts Options, workers int) ([]*AliasPlan, []error) {
+	plans := make([]*AliasPlan, len(jobs))
+	errs := make([]error, len(jobs))
+	parallel(len(jobs), workers, func(i int) {
+		if errs[i] = ctx.Err(); errs[i] == nil {
+			plans[i], errs[i] = PlanJob(ctx, jobs[i], opts)
+		}
+	})
+	return plans, errs
+}
+
+func runEach(ctx context.Context, jobs []Job, workers int, onDone func(Result), run func(i int) Result) []Result {
+	results := make([]Result, len(jobs))
+	var doneMu sync.Mutex
+
+	parallel(len(jobs), workers, func(i int) {
+		result := Result{Job: jobs[i], Err: ctx.Err()}
+		if result.Err == nil {
+			result = run(i)
+		}
+		results[i] = result
+
+		if onDone != nil {
+			doneMu.Lock()
+			onDone(result)
+			doneMu.Unlock()
+		}
+	})
+	return results
+}
+
+// Calls fn for every index below n, with at most workers calls at once.
+func parallel(n int, workers int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package syncer
+
+import "os/exec"
+
+func detachProcessGroup(cmd *exec.Cmd) {}
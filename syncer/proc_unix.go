@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package syncer
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Starts cmd in its own process group, so that a Ctrl-C on the terminal
+// reaches mmsync only and a started commit is finished.
+func detachProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
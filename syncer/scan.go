@@ -0,0 +1,90 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Scan records every regular file and symbolic link below dir. A file whose
+// size, mtime and mode match its entry in previous keeps the recorded hash
+// without being read; pass a nil previous to hash everything. Nested .git
+// directories are skipped, and a missing dir scans as empty. onFile, when
+// set, is called with the number of files recorded so far.
+func Scan(ctx context.Context, dir string, previous map[string]FileState, onFile func(n int)) (map[string]FileState, error) {
+	files := make(map[string]FileState)
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return files, nil
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+
+		state := FileState{Size: info.Size(), ModTime: info.ModTime(), Mode: info.Mode()}
+		if state.Mode&fs.ModeSymlink != 0 {
+			if state.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		if prev, ok := previous[rel]; ok && state.Unchanged(prev) {
+			state.SHA256 = prev.SHA256
+		} else if state.SHA256, err = hashFile(path, state); err != nil {
+			return err
+		}
+		files[rel] = state
+		if onFile != nil {
+			onFile(len(files))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// Hashes the content of a file, or the target of a symbolic link.
+func hashFile(path string, state FileState) (string, error) {
+	h := sha256.New()
+	if state.Mode&fs.ModeSymlink != 0 {
+		h.Write([]byte(state.Link))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
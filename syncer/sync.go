@@ -0,0 +1,422 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bladeacer/mmsync/config"
+)
+
+// Maximum number of changed paths listed in a commit message body.
+const commitBodyLimit = 50
+
+// StateDir returns the directory holding the file manifests and the journal
+// of the profile, next to its database.
+func StateDir(c *config.MnemoConf) string {
+	return filepath.Join(filepath.Dir(c.ConfigSchema.DbPath), "sync")
+}
+
+// Job is one tracked directory to sync.
+type Job struct {
+	ID    string
+	Entry config.DirData
+	Repo  config.Repository
+}
+
+// Options tune a sync run.
+type Options struct {
+	// StateDir holds the file manifests, see StateDir.
+	StateDir string
+	// Rehash ignores the manifest and compares the content of every file
+	// with the copy in the repository.
+	Rehash bool
+	// Xattrs records the user extended attributes of files in the metadata
+	// sidecar.
+	Xattrs bool
+	// Progress, when set, is told what each job is doing. It may be called
+	// from several goroutines at once.
+	Progress func(Progress)
+}
+
+// Stages reported through Options.Progress.
+const (
+	StageScanning   = "scanning"
+	StageCopying    = "copying"
+	StageWaiting    = "waiting for repository"
+	StageCommitting = "committing"
+)
+
+// Progress is what a job is doing. Done and Total count files while
+// scanning, when Total is 0, and changes while copying.
+type Progress struct {
+	Job   Job
+	Stage string
+	Done  int
+	Total int
+}
+
+func (o Options) report(job Job, stage string, done int, total int) {
+	if o.Progress != nil {
+		o.Progress(Progress{Job: job, Stage: stage, Done: done, Total: total})
+	}
+}
+
+// Commits to one repository are made one at a time, since git locks its index.
+var repoLocks sync.Map
+
+func lockRepo(repoPath string) func() {
+	lock, _ := repoLocks.LoadOrStore(repoPath, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// Result is the outcome of syncing one tracked directory.
+type Result struct {
+	Job     Job
+	Changes Changes
+	// Abbreviated hash of the commit made, empty when nothing was committed
+	Commit string
+	Err    error
+}
+
+// Run copies a tracked directory into its folder in the repository, commits
+// the folder and records the files in the manifest. See PlanJob for how the
+// changes are found.
+func Run(ctx context.Context, job Job, opts Options) Result {
+	if err := resetMirror(ctx, job.Repo.Path, job.Entry.Alias); err != nil {
+		return Result{Job: job, Err: err}
+	}
+
+	plan, err := PlanJob(ctx, job, opts)
+	if err != nil {
+		return Result{Job: job, Err: err}
+	}
+	return Execute(ctx, job, plan, opts)
+}
+
+// Execute carries out a plan made for job: it discards uncommitted changes in
+// the repository copy, applies the planned changes, writes the metadata
+// sidecar, commits them and records the planned files in the manifest.
+func Execute(ctx context.Context, job Job, plan *AliasPlan, opts Options) Result {
+	result := Result{Job: job, Changes: plan.Changes}
+	mirror := config.AliasDir(job.Repo.Path, job.Entry.Alias)
+
+	if err := resetMirror(ctx, job.Repo.Path, job.Entry.Alias); err != nil {
+		result.Err = err
+		return result
+	}
+
+	total := plan.Changes.Count()
+	onChange := func(n int) { opts.report(job, StageCopying, n, total) }
+	if err := applyChanges(ctx, plan.Source, mirror, plan.Files, plan.Changes, onChange); err != nil {
+		result.Err = err
+		return result
+	}
+	if err := plan.Metadata.Save(filepath.Join(job.Repo.Path, config.AliasMetadataFile(job.Entry.Alias))); err != nil {
+		result.Err = err
+		return result
+	}
+
+	// Once started, a commit is not cancelled: killing git midway would leave
+	// its index locked.
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	opts.report(job, StageWaiting, 0, 0)
+	unlock := lockRepo(job.Repo.Path)
+	opts.report(job, StageCommitting, 0, 0)
+	commitHash, err := commit(context.Background(), job.Repo.Path, job.Entry.Alias, plan.Changes)
+	unlock()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Commit = commitHash
+
+	tree, err := aliasTree(context.Background(), job.Repo.Path, job.Entry.Alias)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	manifest := &Manifest{
+		ManifestVersion: ManifestVersion,
+		Alias:           job.Entry.Alias,
+		Repo:            job.Repo.Name,
+		SyncedAt:        time.Now().UTC(),
+		Tree:            tree,
+		Files:           plan.Files,
+	}
+	if err := manifest.Save(ManifestPath(opts.StateDir, job.ID)); err != nil {
+		result.Err = err
+	}
+	return result
+}
+
+// Discards uncommitted changes in the alias folder and its metadata sidecar,
+// left by an interrupted sync or made by hand, so that they match their last
+// commit and with it the manifest. The tracked directory is the source of
+// truth, so they would be overwritten anyway.
+func resetMirror(ctx context.Context, repoPath string, alias string) error {
+	paths := mirrorPaths(alias)
+	out, err := git(ctx, repoPath, append([]string{"--no-optional-locks", "status", "--porcelain", "--untracked-files=all", "--"}, paths...)...)
+	if err != nil || out == "" {
+		return err
+	}
+
+	unlock := lockRepo(repoPath)
+	defer unlock()
+
+	// Killing git while it changes the index would leave index.lock behind,
+	// so a started reset runs to the end like a commit does
+	for _, path := range paths {
+		if committed, _ := git(ctx, repoPath, "ls-tree", "HEAD", "--", path); committed != "" {
+			for _, args := range [][]string{
+				{"reset", "-q", "--", path},
+				{"checkout", "-q", "HEAD", "--", path},
+				{"clean", "-f", "-d", "-q", "--", path},
+			} {
+				if _, err := git(context.Background(), repoPath, args...); err != nil {
+					return fmt.Errorf("failed to reset %s to its last commit: %w", path, err)
+				}
+			}
+			continue
+		}
+
+		if _, err := git(context.Background(), repoPath, "rm", "-r", "-q", "--cached", "--ignore-unmatch", "--", path); err != nil {
+			return fmt.Errorf("failed to reset %s: %w", path, err)
+		}
+		if err := os.RemoveAll(filepath.Join(repoPath, path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the alias folder and its metadata sidecar, relative to the
+// repository.
+func mirrorPaths(alias string) []string {
+	return []string{alias, filepath.ToSlash(config.AliasMetadataFile(alias))}
+}
+
+// Applies changes to the repository copy. Deletions come first so that a
+// file replaced by a directory of the same name, or the reverse, works.
+// Every step tolerates having been done already by an interrupted run, and
+// an interrupted run leaves only whole files behind.
+func applyChanges(ctx context.Context, source string, mirror string, files map[string]FileState, c Changes, onChange func(n int)) error {
+	done := 0
+	step := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		onChange(done)
+		done++
+		return nil
+	}
+
+	for _, path := range c.Deleted {
+		if err := step(); err != nil {
+			return err
+		}
+		if err := removeFile(mirror, path); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range c.Renamed {
+		if err := step(); err != nil {
+			return err
+		}
+		from, to := filepath.Join(mirror, filepath.FromSlash(r.From)), filepath.Join(mirror, filepath.FromSlash(r.To))
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return err
+		}
+		err := os.Rename(from, to)
+		if os.IsNotExist(err) {
+			err = copyFile(filepath.Join(source, filepath.FromSlash(r.To)), to, files[r.To])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to rename %s to %s: %w", r.From, r.To, err)
+		}
+		pruneEmptyDirs(mirror, filepath.Dir(from))
+	}
+
+	for _, path := range append(append([]string(nil), c.Added...), c.Modified...) {
+		if err := step(); err != nil {
+			return err
+		}
+		src, dst := filepath.Join(source, filepath.FromSlash(path)), filepath.Join(mirror, filepath.FromSlash(path))
+		if err := copyFile(src, dst, files[path]); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Copies a file or symbolic link into place atomically and gives it the
+// recorded mode and mtime.
+func copyFile(src string, dst string, state FileState) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(dst); err == nil && info.IsDir() {
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+	}
+
+	if state.Mode&fs.ModeSymlink != 0 {
+		if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return os.Symlink(state.Link, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".mmsync-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), state.Mode.Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), state.ModTime, state.ModTime); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func removeFile(mirror string, path string) error {
+	target := filepath.Join(mirror, filepath.FromSlash(path))
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	pruneEmptyDirs(mirror, filepath.Dir(target))
+	return nil
+}
+
+// Removes dir and its parents while they are empty, stopping at root.
+func pruneEmptyDirs(root string, dir string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Stages and commits the alias folder and its metadata sidecar together with
+// the repository's tracking manifest. Returns the abbreviated commit hash, or an empty string
+// when git sees nothing to commit.
+func commit(ctx context.Context, repoPath string, alias string, changes Changes) (string, error) {
+	var pathspecs []string
+	for _, path := range append(mirrorPaths(alias), filepath.ToSlash(filepath.Join(config.MetadataDir, config.ManifestFile))) {
+		if _, err := os.Lstat(filepath.Join(repoPath, path)); err == nil {
+			pathspecs = append(pathspecs, path)
+		} else if _, err := git(ctx, repoPath, "ls-files", "--error-unmatch", "--", path); err == nil {
+			pathspecs = append(pathspecs, path)
+		}
+	}
+	if len(pathspecs) == 0 {
+		return "", nil
+	}
+
+	if _, err := git(ctx, repoPath, append([]string{"add", "-A", "--"}, pathspecs...)...); err != nil {
+		return "", err
+	}
+	_, err := git(ctx, repoPath, append([]string{"diff", "--cached", "--quiet", "--"}, pathspecs...)...)
+	var exitError *exec.ExitError
+	if err == nil {
+		return "", nil
+	} else if !errors.As(err, &exitError) || exitError.ExitCode() != 1 {
+		return "", err
+	}
+
+	args := []string{"commit", "-q", "-m", commitMessage(alias, changes), "--"}
+	if _, err := git(ctx, repoPath, append(args, pathspecs...)...); err != nil {
+		return "", err
+	}
+	return git(ctx, repoPath, "rev-parse", "--short", "HEAD")
+}
+
+func commitMessage(alias string, c Changes) string {
+	var lines []string
+	for _, path := range c.Added {
+		lines = append(lines, "A "+path)
+	}
+	for _, path := range c.Modified {
+		lines = append(lines, "M "+path)
+	}
+	for _, path := range c.Deleted {
+		lines = append(lines, "D "+path)
+	}
+	for _, r := range c.Renamed {
+		lines = append(lines, fmt.Sprintf("R %s -> %s", r.From, r.To))
+	}
+	for _, path := range c.Metadata {
+		lines = append(lines, "P "+path)
+	}
+	if len(lines) > commitBodyLimit {
+		lines = append(lines[:commitBodyLimit], fmt.Sprintf("... and %d more", len(lines)-commitBodyLimit))
+	}
+
+	message := fmt.Sprintf("Sync %s: %s", alias, c.Summary())
+	if len(lines) > 0 {
+		message += "\n\n" + strings.Join(lines, "\n")
+	}
+	return message
+}
+
+// Returns the hash of the tree committed for the alias folder in HEAD, empty
+// when HEAD has none.
+func aliasTree(ctx context.Context, repoPath string, alias string) (string, error) {
+	if _, err := git(ctx, repoPath, "rev-parse", "-q", "--verify", "HEAD"); err != nil {
+		return "", nil
+	}
+	tree, err := git(ctx, repoPath, "rev-parse", "-q", "--verify", "HEAD:"+alias)
+	if err != nil {
+		if tree == "" {
+			return "", nil
+		}
+		return "", err
+	}
+	return tree, nil
+}
+
+// Runs git in repoPath without prompting for anything, in its own process
+// group so that only ctx stops it.
+func git(ctx context.Context, repoPath string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoPath}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	detachProcessGroup(cmd)
+	output, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(output))
+	if err != nil && out != "" {
+		return out, fmt.Errorf("git %s: %w: %s", args[0], err, out)
+	}
+	return out, err
+}
@@ -0,0 +1,54 @@
+//go:build linux
+
+package syncer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"syscall"
+)
+
+// Only user attributes are recorded. The other namespaces hold security
+// labels and ACLs tied to the machine, or need root to set.
+const xattrNamespace = "user."
+
+// Returns the user extended attributes of the file at path, or nil when its
+// filesystem has none.
+func readXattrs(path string) (map[string][]byte, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if errors.Is(err, syscall.ENOTSUP) || size == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]byte, size)
+	if size, err = syscall.Listxattr(path, list); err != nil {
+		return nil, err
+	}
+
+	var attrs map[string][]byte
+	for _, name := range bytes.Split(list[:size], []byte{0}) {
+		if !strings.HasPrefix(string(name), xattrNamespace) {
+			continue
+		}
+		n, err := syscall.Getxattr(path, string(name), nil)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, n)
+		if n, err = syscall.Getxattr(path, string(name), value); err != nil {
+			return nil, err
+		}
+		if attrs == nil {
+			attrs = make(map[string][]byte)
+		}
+		attrs[string(name)] = value[:n]
+	}
+	return attrs, nil
+}
+
+func setXattr(path string, name string, value []byte) error {
+	return syscall.Setxattr(path, name, value, 0)
+}
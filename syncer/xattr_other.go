@@ -0,0 +1,11 @@
+//go:build !linux
+
+package syncer
+
+func readXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func setXattr(path string, name string, value []byte) error {
+	return errUnsupported
+}